@@ -0,0 +1,66 @@
+package cbcparser
+
+import "fmt"
+
+// Severity of an alarm.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+var severityNames = [...]string{
+	SeverityInfo:    "info",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	for i, name := range severityNames {
+		if name == string(text) {
+			*s = Severity(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown severity %q", text)
+}
+
+// Sources of alarms.
+const (
+	SourceInstrument  = "instrument"
+	SourceBackground  = "background"
+	SourceConsistency = "consistency"
+	SourceRanges      = "ranges"
+	SourceAlert       = "alert"
+)
+
+// Alarm is a structured warning attached to a result.
+type Alarm struct {
+	Code        string      `json:"code"`
+	Description string      `json:"description"`
+	Parameters  []Parameter `json:"parameters"`
+	Severity    Severity    `json:"severity"`
+	Source      string      `json:"source"`
+}
+
+func (a Alarm) String() string {
+	return a.Description
+}
+
+// AddAlarm attaches alarm to res.
+func (res *Result) AddAlarm(alarm Alarm) {
+	res.Alarms = append(res.Alarms, alarm)
+}
@@ -0,0 +1,92 @@
+// Package alert notifies staff of critical(panic) CBC values.
+// Its alerters are passed to the parsers with cbcparser.WithAlerters:
+//
+//	log, err := alert.OpenLog("critical.log")
+//	...
+//	results, err := parser.ParseMulti(r, normal_ranges, cbcparser.WithAlerters(log, &alert.Webhook{URL: url}))
+package alert
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abiiranathan/cbcparser/cbcparser"
+)
+
+// Value is a critical value in a Message.
+type Value struct {
+	Parameter cbcparser.Parameter   `json:"parameter"`
+	Value     float32               `json:"value"`
+	Units     string                `json:"units"`
+	Flag      cbcparser.Flag        `json:"flag"`
+	Range     cbcparser.NormalRange `json:"range"`
+}
+
+// Message describes a critical result to the recipients of an alert.
+type Message struct {
+	SampleID   string               `json:"sample_id"`
+	PatientID  string               `json:"patient_id"`
+	Instrument cbcparser.Instrument `json:"instrument"`
+	Timestamp  *time.Time           `json:"timestamp"`
+	Values     []Value              `json:"values"`
+}
+
+// NewMessage returns the message of a critical result.
+func NewMessage(c cbcparser.CriticalResult) Message {
+	res := c.Result
+	m := Message{
+		SampleID:   res.SampleID,
+		PatientID:  res.PatientID,
+		Instrument: res.Instrument,
+		Timestamp:  res.Timestamp,
+	}
+
+	for _, p := range c.Parameters {
+		v := res.Values[p]
+		m.Values = append(m.Values, Value{
+			Parameter: p,
+			Value:     v.Value,
+			Units:     v.Units,
+			Flag:      v.Flag,
+			Range:     v.NormalRange,
+		})
+	}
+	return m
+}
+
+// Subject returns a one-line summary e.g "Critical CBC result for sample 12".
+func (m Message) Subject() string {
+	return fmt.Sprintf("Critical CBC result for sample %s", m.SampleID)
+}
+
+// String returns the message as text, one critical value per line.
+func (m Message) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Sample: %s\n", m.SampleID)
+	if m.PatientID != "" {
+		fmt.Fprintf(&b, "Patient: %s\n", m.PatientID)
+	}
+
+	fmt.Fprintf(&b, "Instrument: %s %s\n", m.Instrument.Manufacturer, m.Instrument.Model)
+	if m.Timestamp != nil {
+		fmt.Fprintf(&b, "Analysed: %s\n", m.Timestamp.Format("2006-01-02 15:04"))
+	}
+
+	for _, v := range m.Values {
+		fmt.Fprintf(&b, "%s %g %s %s(critical limits %s)\n", v.Parameter, v.Value, v.Units, v.Flag, limits(v.Range))
+	}
+	return b.String()
+}
+
+func limits(nr cbcparser.NormalRange) string {
+	lower, upper := "-", "-"
+	if nr.CriticalLower != 0 {
+		lower = fmt.Sprint(nr.CriticalLower)
+	}
+
+	if nr.CriticalUpper != 0 {
+		upper = fmt.Sprint(nr.CriticalUpper)
+	}
+	return lower + " to " + upper
+}
@@ -0,0 +1,245 @@
+package alert
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/cbcparser/cbcparser"
+	"github.com/abiiranathan/cbcparser/cbcparser/human"
+)
+
+func critical() cbcparser.CriticalResult {
+	ts := time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+	res := cbcparser.NewResult(cbcparser.Instrument{Manufacturer: "Human Diagnostics", Model: "HumaCount 30TS"})
+	res.SampleID = "12"
+	res.PatientID = "P-001"
+	res.Timestamp = &ts
+	res.Set(cbcparser.HGB, cbcparser.CBCValue{
+		Value:       4.2,
+		Units:       "g/dL",
+		Flag:        cbcparser.FlagCriticalLow,
+		NormalRange: cbcparser.NormalRange{Lower: 12, Upper: 17, CriticalLower: 5},
+	})
+	res.Set(cbcparser.PLT, cbcparser.CBCValue{
+		Value:       1200,
+		Units:       "10^9/L",
+		Flag:        cbcparser.FlagCriticalHigh,
+		NormalRange: cbcparser.NormalRange{Lower: 150, Upper: 400, CriticalUpper: 1000},
+	})
+
+	return cbcparser.CriticalResult{
+		Result:     res,
+		Parameters: []cbcparser.Parameter{cbcparser.HGB, cbcparser.PLT},
+	}
+}
+
+func TestLog(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewLog(&buf).Alert(critical()); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+
+	for i, want := range []string{"HGB=4.2 g/dL\tcritical_low", "PLT=1200 10^9/L\tcritical_high"} {
+		if !strings.Contains(lines[i], "sample=12\tpatient=P-001\t"+want) {
+			t.Errorf("line %d = %q, want it to contain %q", i+1, lines[i], want)
+		}
+	}
+}
+
+func TestAlertAfterImport(t *testing.T) {
+	data, err := os.ReadFile("../../sample_data/human.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nr := &cbcparser.CBCNormalRange{
+		PLT: cbcparser.NormalRange{Lower: 150, Upper: 350, CriticalLower: 100},
+	}
+
+	var buf bytes.Buffer
+	_, err = human.NewMultiParser().ParseMulti(bytes.NewReader(append(data, "AUTO_00005\t17/09/2021\n"...)), nr,
+		cbcparser.WithAlerters(NewLog(&buf)))
+	if err == nil {
+		t.Fatal("want an error for the truncated row")
+	}
+
+	if buf.Len() > 0 {
+		t.Fatalf("failed import sent alerts:\n%s", buf.String())
+	}
+
+	results, err := human.NewMultiParser().ParseMulti(bytes.NewReader(data), nr, cbcparser.WithAlerters(NewLog(&buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var critical int
+	for _, res := range results {
+		if len(res.Critical()) > 0 {
+			critical++
+		}
+	}
+
+	if lines := strings.Count(buf.String(), "\n"); critical == 0 || lines != critical {
+		t.Fatalf("got %d alerts for %d critical results", lines, critical)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var (
+		got    Message
+		header http.Header
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	wh := &Webhook{URL: srv.URL, Header: http.Header{"Authorization": {"Bearer secret"}}}
+	if err := wh.Alert(critical()); err != nil {
+		t.Fatal(err)
+	}
+
+	if ct := header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	if auth := header.Get("Authorization"); auth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want the configured header", auth)
+	}
+
+	if got.SampleID != "12" || got.PatientID != "P-001" || len(got.Values) != 2 {
+		t.Fatalf("unexpected message %+v", got)
+	}
+
+	if v := got.Values[0]; v.Parameter != cbcparser.HGB || v.Flag != cbcparser.FlagCriticalLow || v.Range.CriticalLower != 5 {
+		t.Errorf("unexpected value %+v", v)
+	}
+}
+
+func TestWebhookStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	err := (&Webhook{URL: srv.URL}).Alert(critical())
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("got error %v, want 503 status", err)
+	}
+}
+
+func TestWebhookDefaultClient(t *testing.T) {
+	if defaultClient == http.DefaultClient || defaultClient.Timeout == 0 {
+		t.Fatal("webhooks without a client must use a client with a timeout")
+	}
+}
+
+// smtp_server accepts one message on a local listener and sends its
+// envelope and data on the returned channel.
+func smtp_server(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	mail := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+		var b strings.Builder
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+				b.WriteString(line)
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					b.WriteString(line)
+				}
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				mail <- b.String()
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return ln.Addr().String(), mail
+}
+
+func TestSMTP(t *testing.T) {
+	addr, mail := smtp_server(t)
+
+	s := &SMTP{Addr: addr, From: "lab@example.com", To: []string{"ward@example.com", "doctor@example.com"}}
+	if err := s.Alert(critical()); err != nil {
+		t.Fatal(err)
+	}
+
+	var got string
+	select {
+	case got = <-mail:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received")
+	}
+
+	for _, want := range []string{
+		"MAIL FROM:<lab@example.com>",
+		"RCPT TO:<ward@example.com>",
+		"RCPT TO:<doctor@example.com>",
+		"To: ward@example.com, doctor@example.com\r\n",
+		"Subject: Critical CBC result for sample 12\r\n",
+		"HGB 4.2 g/dL critical_low(critical limits 5 to -)\r\n",
+		"PLT 1200 10^9/L critical_high(critical limits - to 1000)\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("mail does not contain %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package alert
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abiiranathan/cbcparser/cbcparser"
+)
+
+// Log appends a line per critical value to a log file.
+type Log struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewLog returns an alerter writing to w.
+func NewLog(w io.Writer) *Log {
+	return &Log{w: w}
+}
+
+// OpenLog opens or creates the log file name for appending.
+func OpenLog(name string) (*Log, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewLog(f), nil
+}
+
+// Alert writes the critical values of c to the log with the time of notification.
+func (l *Log) Alert(c cbcparser.CriticalResult) error {
+	m := NewMessage(c)
+	now := time.Now().Format(time.RFC3339)
+
+	var b strings.Builder
+	for _, v := range m.Values {
+		fmt.Fprintf(&b, "%s\tsample=%s\tpatient=%s\t%s=%g %s\t%s\n",
+			now, m.SampleID, m.PatientID, v.Parameter, v.Value, v.Units, v.Flag)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err := io.WriteString(l.w, b.String())
+	return err
+}
+
+// Close closes the log file if it was opened with OpenLog or implements io.Closer.
+func (l *Log) Close() error {
+	if c, ok := l.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package alert
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	"github.com/abiiranathan/cbcparser/cbcparser"
+)
+
+// SMTP e-mails critical results as plain text.
+type SMTP struct {
+	Addr string    // Server address e.g mail.example.com:587
+	Auth smtp.Auth // nil for servers without authentication
+	From string
+	To   []string
+}
+
+// Alert sends c to the recipients.
+func (s *SMTP) Alert(c cbcparser.CriticalResult) error {
+	m := NewMessage(c)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", m.Subject())
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(m.String(), "\n", "\r\n"))
+
+	return smtp.SendMail(s.Addr, s.Auth, s.From, s.To, []byte(b.String()))
+}
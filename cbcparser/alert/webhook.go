@@ -0,0 +1,58 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/abiiranathan/cbcparser/cbcparser"
+)
+
+// defaultClient is used by webhooks without a client, so that an
+// unresponsive server does not hold up parsing.
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// Webhook posts critical results as a JSON Message to URL.
+type Webhook struct {
+	URL    string
+	Header http.Header  // Extra headers e.g Authorization
+	Client *http.Client // Defaults to a client with a 10s timeout
+}
+
+// Alert posts c to the webhook. Responses other than 2xx are errors.
+func (wh *Webhook) Alert(c cbcparser.CriticalResult) error {
+	body, err := json.Marshal(NewMessage(c))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	for k, v := range wh.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := wh.Client
+	if client == nil {
+		client = defaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s: %s", wh.URL, resp.Status)
+	}
+	return nil
+}
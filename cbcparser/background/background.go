@@ -0,0 +1,227 @@
+// Package background checks the background counts of blank runs and
+// keeps a per-day log of the results for accreditation records.
+//
+// Blank runs are only returned by the parsers when selected:
+//
+//	results, err := parser.ParseMulti(r, normal_ranges,
+//		cbcparser.WithRecordTypes(cbcparser.RecordPatient, cbcparser.RecordBlank))
+package background
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abiiranathan/cbcparser/cbcparser"
+	"github.com/abiiranathan/cbcparser/cbcparser/units"
+)
+
+// AlarmCode is the code of the alarm attached to patient results
+// analysed after a failed blank run.
+const AlarmCode = "BLANK_FAILED"
+
+// Limit is the maximum acceptable background count of a parameter.
+type Limit struct {
+	Max float32 `json:"max"`
+
+	// Units of Max e.g "10^9/L". Counts in other units are compared with Max
+	// converted to their units, and counts whose units cannot be converted
+	// are not checked. Empty compares counts in any units with Max.
+	Units string `json:"units,omitempty"`
+}
+
+// Limits are the maximum acceptable background counts of a blank run.
+type Limits map[cbcparser.Parameter]Limit
+
+// DefaultLimits returns commonly used background limits for
+// 3-part differential analysers.
+func DefaultLimits() Limits {
+	return Limits{
+		cbcparser.WBC: {Max: 0.3, Units: "10^9/L"},
+		cbcparser.RBC: {Max: 0.03, Units: "10^12/L"},
+		cbcparser.HGB: {Max: 0.3, Units: "g/dL"},
+		cbcparser.PLT: {Max: 10, Units: "10^9/L"},
+	}
+}
+
+// in returns the limit in the units of v.
+func (l Limit) in(p cbcparser.Parameter, v cbcparser.CBCValue) (float32, bool) {
+	if l.Units == "" {
+		return l.Max, true
+	}
+
+	f, err := units.Factor(p, l.Units, v.Units)
+	if err != nil {
+		return 0, false
+	}
+	return float32(float64(l.Max) * f), true
+}
+
+// Failure is a background count above its limit.
+type Failure struct {
+	Parameter cbcparser.Parameter `json:"parameter"`
+	Value     float32             `json:"value"`
+	Limit     float32             `json:"limit"` // In Units
+	Units     string              `json:"units"`
+}
+
+func (f Failure) String() string {
+	return strings.TrimSpace(fmt.Sprintf("%s %g > %g %s", f.Parameter, f.Value, f.Limit, f.Units))
+}
+
+// Check is the outcome of a single blank run.
+type Check struct {
+	SampleID   string               `json:"sample_id"`
+	Instrument cbcparser.Instrument `json:"instrument"`
+	Timestamp  *time.Time           `json:"timestamp"`
+	Passed     bool                 `json:"passed"`
+	Failures   []Failure            `json:"failures"`
+}
+
+// Day summarises the blank runs of one instrument on one day.
+// A day passes when its last blank run passed.
+type Day struct {
+	Date       string               `json:"date"` // 2006-01-02
+	Instrument cbcparser.Instrument `json:"instrument"`
+	Passed     bool                 `json:"passed"`
+	Checks     []Check              `json:"checks"`
+}
+
+// Tracker checks blank runs against Limits and warns about patient
+// results analysed after a failed blank on the same instrument and day.
+// Results must be observed in order of analysis.
+type Tracker struct {
+	Limits Limits
+
+	checks []Check
+	last   map[cbcparser.Instrument]int // index of the latest check per instrument
+}
+
+// NewTracker returns a tracker using limits. A nil limits uses DefaultLimits.
+func NewTracker(limits Limits) *Tracker {
+	if limits == nil {
+		limits = DefaultLimits()
+	}
+	return &Tracker{Limits: limits, last: make(map[cbcparser.Instrument]int)}
+}
+
+// Check compares the counts of a blank run with the limits in their units.
+// Counts that were not measured are ignored.
+func (t *Tracker) Check(res *cbcparser.Result) Check {
+	check := Check{
+		SampleID:   res.SampleID,
+		Instrument: res.Instrument,
+		Timestamp:  res.Timestamp,
+		Passed:     true,
+	}
+
+	for _, p := range cbcparser.Parameters() {
+		limit, ok := t.Limits[p]
+		if !ok {
+			continue
+		}
+
+		v, ok := res.Value(p)
+		if !ok || !v.Valid() {
+			continue
+		}
+
+		upper, ok := limit.in(p, v)
+		if ok && v.Value > upper {
+			check.Passed = false
+			check.Failures = append(check.Failures, Failure{Parameter: p, Value: v.Value, Limit: upper, Units: v.Units})
+		}
+	}
+	return check
+}
+
+// Observe records blank runs and attaches an alarm to patient results
+// analysed after a failed blank run on the same instrument and day.
+// Other record types are ignored.
+func (t *Tracker) Observe(res *cbcparser.Result) {
+	switch res.RecordType {
+	case cbcparser.RecordBlank:
+		t.last[res.Instrument] = len(t.checks)
+		t.checks = append(t.checks, t.Check(res))
+	case cbcparser.RecordPatient:
+		i, ok := t.last[res.Instrument]
+		if !ok {
+			return
+		}
+
+		check := t.checks[i]
+		if check.Passed || day(check.Timestamp) != day(res.Timestamp) {
+			return
+		}
+
+		failures := make([]string, len(check.Failures))
+		params := make([]cbcparser.Parameter, len(check.Failures))
+		for i, f := range check.Failures {
+			failures[i] = f.String()
+			params[i] = f.Parameter
+		}
+
+		res.AddAlarm(cbcparser.Alarm{
+			Code:        AlarmCode,
+			Description: "Analysed after a failed blank run (" + strings.Join(failures, ", ") + ")",
+			Parameters:  params,
+			Severity:    cbcparser.SeverityWarning,
+			Source:      cbcparser.SourceBackground,
+		})
+	}
+}
+
+// Checks returns all blank runs observed so far.
+func (t *Tracker) Checks() []Check {
+	return t.checks
+}
+
+// Log returns the blank runs grouped by instrument and day,
+// ordered by date.
+func (t *Tracker) Log() []Day {
+	type key struct {
+		date       string
+		instrument cbcparser.Instrument
+	}
+
+	index := make(map[key]int)
+	var days []Day
+
+	for _, check := range t.checks {
+		k := key{day(check.Timestamp), check.Instrument}
+		i, ok := index[k]
+		if !ok {
+			i = len(days)
+			index[k] = i
+			days = append(days, Day{Date: k.date, Instrument: k.instrument})
+		}
+
+		days[i].Checks = append(days[i].Checks, check)
+		days[i].Passed = check.Passed
+	}
+
+	sort.SliceStable(days, func(i, j int) bool {
+		return days[i].Date < days[j].Date
+	})
+	return days
+}
+
+// Process sorts results by time of analysis and observes them with
+// a new tracker using limits. It returns the per-day log.
+func Process(results cbcparser.Results, limits Limits) []Day {
+	results.SortByTime()
+
+	t := NewTracker(limits)
+	for _, res := range results {
+		t.Observe(res)
+	}
+	return t.Log()
+}
+
+func day(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
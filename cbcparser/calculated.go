@@ -0,0 +1,39 @@
+package cbcparser
+
+// Provenance of calculated values.
+const ProvenanceCalculated = "calculated"
+
+// CalculatedValue is a value derived from the measured values of a result
+// e.g the Mentzer index. See package indices.
+type CalculatedValue struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Value       float32 `json:"value"`
+	Units       string  `json:"units"`
+	Provenance  string  `json:"provenance"`
+}
+
+// AddCalculated attaches v to res, replacing a calculated value with the same name.
+func (res *Result) AddCalculated(v CalculatedValue) {
+	if v.Provenance == "" {
+		v.Provenance = ProvenanceCalculated
+	}
+
+	for i := range res.Calculated {
+		if res.Calculated[i].Name == v.Name {
+			res.Calculated[i] = v
+			return
+		}
+	}
+	res.Calculated = append(res.Calculated, v)
+}
+
+// CalculatedValue returns the calculated value with name and whether it exists.
+func (res *Result) CalculatedValue(name string) (CalculatedValue, bool) {
+	for _, v := range res.Calculated {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return CalculatedValue{}, false
+}
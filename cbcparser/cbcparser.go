@@ -1,7 +1,6 @@
 package cbcparser
 
 import (
-	"encoding/json"
 	"errors"
 	"io"
 )
@@ -33,13 +32,36 @@ type CBCMultiWriter []CBCWriter
 type NormalRange struct {
 	Lower float32 `json:"lower"`
 	Upper float32 `json:"upper"`
+
+	// Critical(panic) limits requiring immediate notification. 0 means no limit.
+	CriticalLower float32 `json:"critical_lower,omitempty"`
+	CriticalUpper float32 `json:"critical_upper,omitempty"`
 }
 
 type CBCValue struct {
 	Value       float32     `json:"value"`
 	Units       string      `json:"units"`
-	Flag        string      `json:"flag"`
+	UCUM        string      `json:"ucum"` // UCUM code of Units e.g 10*9/L
+	LOINC       *Code       `json:"loinc,omitempty"`
+	Flag        Flag        `json:"flag"`
 	NormalRange NormalRange `json:"normal_range"`
+
+	// The flag code exported by the machine e.g "L" or "E".
+	// Empty when the flag was computed from the normal range.
+	RawFlag string `json:"raw_flag"`
+
+	// Whether Value holds a measured value. Value is 0 and encoded
+	// as null in JSON when the value is missing.
+	Status ValueStatus `json:"status"`
+	// The raw cell of values that could not be parsed or were
+	// rejected or outside the measuring range.
+	Raw string `json:"raw,omitempty"`
+
+	// ">" or "<" when Value is the limit of the measuring range
+	// that the measured value exceeded.
+	Comparator string `json:"comparator,omitempty"`
+	// Why the value was rejected or replaced by a limit.
+	Reason string `json:"reason,omitempty"`
 }
 
 type CBCNormalRange struct {
@@ -74,42 +96,40 @@ type CBCNormalRange struct {
 
 // Parses a csv file with a single CBC record.
 type CSVParser interface {
-	Parse(r io.Reader, normal_ranges *CBCNormalRange) (CBCWriter, error)
+	Parse(r io.Reader, normal_ranges *CBCNormalRange, opts ...Option) (*Result, error)
 }
 
-// Parses a csv with multiple rows and returns a slice of CBCWriter structs.
+// Parses a csv with multiple rows and returns a slice of results.
+// With WithSkipBadRows, the good records are returned together with RowErrors.
 type CSVMultiParser interface {
-	ParseMulti(r io.Reader, normal_ranges *CBCNormalRange) (CBCMultiWriter, error)
+	ParseMulti(r io.Reader, normal_ranges *CBCNormalRange, opts ...Option) (Results, error)
 }
 
 func (list CBCMultiWriter) Write(out io.Writer, format OutFormat) error {
-	var data []byte
+	return write(out, list, format)
+}
 
-	if format == JSON {
-		d, err := json.Marshal(list)
-		if err != nil {
-			return err
+// RecordReader reads CBC records one row at a time, keeping memory
+// usage constant regardless of the size of the export.
+// Callers may stop reading at any time.
+type RecordReader interface {
+	// Next returns the next result or io.EOF when there are no more records.
+	// Errors for individual rows are returned as *ParseError.
+	Next() (*Result, error)
+}
+
+// ReadAll reads all remaining records from rr.
+func ReadAll(rr RecordReader) (Results, error) {
+	var results Results
+	for {
+		res, err := rr.Next()
+		if err == io.EOF {
+			return results, nil
 		}
-		data = d
-	} else if format == JSONIndent {
-		d, err := json.MarshalIndent(list, "", "	")
+
 		if err != nil {
-			return err
+			return nil, err
 		}
-		data = d
-	} else {
-		return ErrInvalidOutFormat
-	}
-
-	_, err := out.Write(data)
-	return err
-}
-
-func ReadNormalRanges(r io.Reader) (*CBCNormalRange, error) {
-	var normal_ranges CBCNormalRange
-	err := json.NewDecoder(r).Decode(&normal_ranges)
-	if err != nil {
-		return nil, err
+		results = append(results, res)
 	}
-	return &normal_ranges, nil
 }
@@ -0,0 +1,98 @@
+package cbcparser
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrMissingColumns = errors.New("missing expected columns")
+
+// MissingColumnsError lists the expected columns not found in a header row.
+type MissingColumnsError struct {
+	Columns []string
+}
+
+func (e *MissingColumnsError) Error() string {
+	return ErrMissingColumns.Error() + ": " + strings.Join(e.Columns, ", ")
+}
+
+func (e *MissingColumnsError) Is(target error) bool {
+	return target == ErrMissingColumns
+}
+
+// Columns locates cells by the label of their header instead of their position,
+// so that extra or reordered columns do not misassign values.
+// Labels are matched case-insensitively.
+type Columns struct {
+	index map[string]int
+	units map[string]string
+}
+
+// NewColumns indexes the header row. split separates each header into
+// its label and units e.g "HGB(g/dL)" into "HGB" and "g/dL".
+// Unknown columns are indexed too but never looked up.
+func NewColumns(headers []string, split func(header string) (label, units string)) *Columns {
+	cols := &Columns{
+		index: make(map[string]int, len(headers)),
+		units: make(map[string]string, len(headers)),
+	}
+
+	for i, header := range headers {
+		label, units := split(strings.TrimSpace(header))
+		key := strings.ToUpper(label)
+
+		// Keep the first occurrence of duplicated headers.
+		if _, ok := cols.index[key]; ok {
+			continue
+		}
+		cols.index[key] = i
+		cols.units[key] = units
+	}
+	return cols
+}
+
+// Has reports whether a column with label exists.
+func (cols *Columns) Has(label string) bool {
+	_, ok := cols.index[strings.ToUpper(label)]
+	return ok
+}
+
+// Index returns the position of the column with label.
+func (cols *Columns) Index(label string) (int, bool) {
+	i, ok := cols.index[strings.ToUpper(label)]
+	return i, ok
+}
+
+// Get returns the cell of row under label or an empty string
+// if the column does not exist.
+func (cols *Columns) Get(row []string, label string) string {
+	i, ok := cols.index[strings.ToUpper(label)]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return row[i]
+}
+
+// Units returns the units declared in the header of label.
+func (cols *Columns) Units(label string) string {
+	return cols.units[strings.ToUpper(label)]
+}
+
+// Missing returns the labels that are not present in the header.
+func (cols *Columns) Missing(labels ...string) []string {
+	var missing []string
+	for _, label := range labels {
+		if !cols.Has(label) {
+			missing = append(missing, label)
+		}
+	}
+	return missing
+}
+
+// Require returns a *MissingColumnsError if any of labels is not present.
+func (cols *Columns) Require(labels ...string) error {
+	if missing := cols.Missing(labels...); len(missing) > 0 {
+		return &MissingColumnsError{Columns: missing}
+	}
+	return nil
+}
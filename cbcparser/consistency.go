@@ -0,0 +1,120 @@
+package cbcparser
+
+import "fmt"
+
+// Codes of the alarms raised by CheckConsistency.
+const (
+	AlarmMCVMismatch  = "MCV_MISMATCH"
+	AlarmMCHMismatch  = "MCH_MISMATCH"
+	AlarmMCHCMismatch = "MCHC_MISMATCH"
+	AlarmMCHCHigh     = "MCHC_HIGH"
+)
+
+// Tolerances of the consistency checks between red cell indices.
+// Differences are relative to the reported value e.g 0.05 allows 5%.
+type Tolerances struct {
+	MCV  float32 // MCV ≈ HCT×10/RBC
+	MCH  float32 // MCH ≈ HGB×10/RBC
+	MCHC float32 // MCHC ≈ HGB×100/HCT
+
+	// MCHC in g/dL above which lipaemia or agglutination is suspected. 0 disables the check.
+	MCHCLimit float32
+}
+
+// DefaultTolerances returns the tolerances used unless WithTolerances is given.
+func DefaultTolerances() *Tolerances {
+	return &Tolerances{
+		MCV:       0.05,
+		MCH:       0.05,
+		MCHC:      0.05,
+		MCHCLimit: 37,
+	}
+}
+
+// WithTolerances sets the tolerances of the consistency checks run on every
+// patient result. A nil t disables the checks.
+func WithTolerances(t *Tolerances) Option {
+	return func(o *Options) {
+		o.Tolerances = t
+	}
+}
+
+// Units the relationships are written in. The machines export these units,
+// values in other units(e.g after conversion to SI) are not checked.
+var consistencyUnits = map[Parameter][]string{
+	RBC:  {"10*12/L", "10*6/uL"},
+	HGB:  {"g/dL"},
+	HCT:  {"%"},
+	MCV:  {"fL"},
+	MCH:  {"pg"},
+	MCHC: {"g/dL"},
+}
+
+// CheckConsistency recomputes MCV, MCH and MCHC from RBC, HGB and HCT and
+// returns an alarm for each reported index that differs by more than t.
+func CheckConsistency(res *Result, t *Tolerances) []Alarm {
+	var alarms []Alarm
+
+	get := func(p Parameter) (float32, bool) {
+		v, ok := res.Value(p)
+		if !ok || !v.Valid() {
+			return 0, false
+		}
+
+		for _, u := range consistencyUnits[p] {
+			if v.UCUM == u {
+				return v.Value, true
+			}
+		}
+		return 0, false
+	}
+
+	check := func(code string, p Parameter, want float32, tolerance float32, params ...Parameter) {
+		got, ok := get(p)
+		if !ok || got == 0 {
+			return
+		}
+
+		diff := (got - want) / got
+		if diff < 0 {
+			diff = -diff
+		}
+
+		if diff > tolerance {
+			alarms = append(alarms, Alarm{
+				Code:        code,
+				Description: fmt.Sprintf("%s %g differs from calculated %.3g by %.0f%%", p, got, want, diff*100),
+				Parameters:  append([]Parameter{p}, params...),
+				Severity:    SeverityWarning,
+				Source:      SourceConsistency,
+			})
+		}
+	}
+
+	rbc, hasRBC := get(RBC)
+	hgb, hasHGB := get(HGB)
+	hct, hasHCT := get(HCT)
+
+	if hasHCT && hasRBC && rbc > 0 {
+		check(AlarmMCVMismatch, MCV, hct*10/rbc, t.MCV, HCT, RBC)
+	}
+
+	if hasHGB && hasRBC && rbc > 0 {
+		check(AlarmMCHMismatch, MCH, hgb*10/rbc, t.MCH, HGB, RBC)
+	}
+
+	if hasHGB && hasHCT && hct > 0 {
+		check(AlarmMCHCMismatch, MCHC, hgb*100/hct, t.MCHC, HGB, HCT)
+	}
+
+	if mchc, ok := get(MCHC); ok && t.MCHCLimit > 0 && mchc > t.MCHCLimit {
+		alarms = append(alarms, Alarm{
+			Code:        AlarmMCHCHigh,
+			Description: fmt.Sprintf("MCHC > %g: possible lipaemia/agglutination", t.MCHCLimit),
+			Parameters:  []Parameter{MCHC},
+			Severity:    SeverityWarning,
+			Source:      SourceConsistency,
+		})
+	}
+	return alarms
+}
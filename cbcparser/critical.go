@@ -0,0 +1,95 @@
+package cbcparser
+
+import "fmt"
+
+// AlarmAlertFailed is the code of the alarm attached to critical results
+// when an Alerter failed.
+const AlarmAlertFailed = "ALERT_FAILED"
+
+// CriticalResult is a patient result with values beyond their critical limits.
+type CriticalResult struct {
+	Result     *Result
+	Parameters []Parameter // Critical parameters in report order
+}
+
+// Alerter notifies staff of a critical result e.g by e-mail.
+// Package alert has implementations for a log file, webhooks and SMTP.
+type Alerter interface {
+	Alert(c CriticalResult) error
+}
+
+// WithAlerters adds alerters invoked for every patient result with critical values
+// once Parse or ParseMulti has read the file, so that an import that fails on a bad
+// row notifies nobody and can be repeated. A failed alert is attached to the result
+// as an alarm. Records read one at a time with Next are not alerted; pass them to
+// Alert once they are stored.
+func WithAlerters(alerters ...Alerter) Option {
+	return func(o *Options) {
+		o.Alerters = append(o.Alerters, alerters...)
+	}
+}
+
+// ApplyCriticalLimits flags the values of res beyond the critical limits of their
+// range and returns the critical parameters, including those flagged by the machine.
+func ApplyCriticalLimits(res *Result) []Parameter {
+	var critical []Parameter
+	for _, p := range res.Parameters() {
+		v := res.Values[p]
+		if (v.Valid() || v.Comparator != "") && v.Flag != FlagInvalid && v.Flag != FlagSuspect {
+			if f := FlagFor(v.Value, v.NormalRange); f.IsCritical() {
+				v.Flag = f
+				res.Values[p] = v
+			}
+		}
+
+		if v.Flag.IsCritical() {
+			critical = append(critical, p)
+		}
+	}
+	return critical
+}
+
+// Critical returns the parameters of res flagged critical in report order.
+func (res *Result) Critical() []Parameter {
+	var critical []Parameter
+	for _, p := range res.Parameters() {
+		if res.Values[p].Flag.IsCritical() {
+			critical = append(critical, p)
+		}
+	}
+	return critical
+}
+
+// Alert invokes alerters for every patient result in results with critical values.
+// Failed alerts are attached to the result as alarms.
+func Alert(results Results, alerters ...Alerter) {
+	if len(alerters) == 0 {
+		return
+	}
+
+	for _, res := range results {
+		if res.RecordType != RecordPatient {
+			continue
+		}
+
+		if critical := res.Critical(); len(critical) > 0 {
+			alert(res, critical, alerters)
+		}
+	}
+}
+
+// alert invokes alerters for the critical parameters of res.
+func alert(res *Result, critical []Parameter, alerters []Alerter) {
+	c := CriticalResult{Result: res, Parameters: critical}
+	for _, a := range alerters {
+		if err := a.Alert(c); err != nil {
+			res.AddAlarm(Alarm{
+				Code:        AlarmAlertFailed,
+				Description: fmt.Sprintf("Critical value notification failed: %s", err),
+				Parameters:  critical,
+				Severity:    SeverityError,
+				Source:      SourceAlert,
+			})
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package cbcparser
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// DateOrder is the order of the day and month in dates exported by a machine.
+type DateOrder int
+
+const (
+	DayFirst   DateOrder = iota // 17/09/2021
+	MonthFirst                  // 09/17/2021
+)
+
+// Default time layouts tried in order when Options.TimeLayout is not set.
+var defaultTimeLayouts = []string{"15:04", "15:04:05"}
+
+func (o *Options) date_layout() string {
+	if o.DateLayout != "" {
+		return o.DateLayout
+	}
+
+	if o.DateOrder == MonthFirst {
+		return "01/02/2006"
+	}
+	return "02/01/2006"
+}
+
+func (o *Options) location() *time.Location {
+	if o.Location != nil {
+		return o.Location
+	}
+	return time.Local
+}
+
+// IsNullDate reports whether value is an empty or placeholder date e.g "00/00/0000".
+func IsNullDate(value string) bool {
+	return strings.Trim(value, "0/-.: ") == ""
+}
+
+// ParseDate parses a date exported by the machine in the configured layout and
+// time zone. Null dates are returned as nil.
+func (o *Options) ParseDate(value string) (*time.Time, error) {
+	return o.ParseDateTime(value, "")
+}
+
+// ParseDateTime parses a date and time of day exported by the machine in the
+// configured layouts and time zone. Null dates are returned as nil.
+// An empty clock is parsed as midnight.
+func (o *Options) ParseDateTime(date, clock string) (*time.Time, error) {
+	date, clock = strings.TrimSpace(date), strings.TrimSpace(clock)
+	if IsNullDate(date) {
+		return nil, nil
+	}
+
+	if clock == "" {
+		t, err := time.ParseInLocation(o.date_layout(), date, o.location())
+		if err != nil {
+			return nil, err
+		}
+		return &t, nil
+	}
+
+	layouts := defaultTimeLayouts
+	if o.TimeLayout != "" {
+		layouts = []string{o.TimeLayout}
+	}
+
+	var first error
+	for _, layout := range layouts {
+		t, err := time.ParseInLocation(o.date_layout()+" "+layout, date+" "+clock, o.location())
+		if err == nil {
+			return &t, nil
+		}
+
+		if first == nil {
+			first = err
+		}
+	}
+	return nil, first
+}
+
+// SortByTime sorts list by time of analysis. Results without a timestamp are
+// placed last and the order of equal results is preserved.
+func (list Results) SortByTime() {
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i].Timestamp, list[j].Timestamp
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return a.Before(*b)
+	})
+}
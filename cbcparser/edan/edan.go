@@ -3,18 +3,17 @@
 // https://theasciicode.com.ar/extended-ascii-code/box-drawing-character-single-line-upper-left-corner-ascii-code-218.html
 //
 // CBC format:
-// Sample ID,Mode,Analysis Time,WBC(10^3/��L),LYM#(10^3/��L),LYM%(%),MXD#(),MXD%(),NEUT#(),NEUT%(),RBC(10^6/��L),HGB(g/dL),HCT(%),MCV(fL),MCH(pg),MCHC(g/dL),RDW_CV(%),RDW_SD(fL),PLT(10^3/��L),PDW(fL),MPV(fL),PCT(%),P_LCR(%),P_LCC(10^3/��L)
+// Sample ID,Mode,Analysis Time,WBC(10^3/µL),LYM#(10^3/µL),LYM%(%),MXD#(),MXD%(),NEUT#(),NEUT%(),RBC(10^6/µL),HGB(g/dL),HCT(%),MCV(fL),MCH(pg),MCHC(g/dL),RDW_CV(%),RDW_SD(fL),PLT(10^3/µL),PDW(fL),MPV(fL),PCT(%),P_LCR(%),P_LCC(10^3/µL)
 package edan
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"io"
 	"regexp"
-	"strconv"
 	"strings"
 
 	"github.com/abiiranathan/cbcparser/cbcparser"
+	"github.com/abiiranathan/cbcparser/cbcparser/units"
 )
 
 var (
@@ -23,23 +22,68 @@ var (
 	UnitsRegex = regexp.MustCompile(`\((.*?)\)`)
 )
 
+// Default field separator, see cbcparser.WithFieldSeparator.
+const separator = ','
+
+// Labels of the identifier columns.
 const (
-	nfields   = 24
-	separator = ','
+	colSampleID     = "Sample ID"
+	colMode         = "Mode"
+	colAnalysisTime = "Analysis Time"
 )
 
-func parse_float(value string) float32 {
-	fvalue, err := strconv.ParseFloat(value, 32)
-	if err != nil {
-		return 0.0
+// Analyte columns keyed by the label used in the header e.g "HGB(g/dL)".
+var analytes = []struct {
+	param cbcparser.Parameter
+	label string
+}{
+	{cbcparser.WBC, "WBC"},
+	{cbcparser.LYM, "LYM#"},
+	{cbcparser.LYMPercent, "LYM%"},
+	{cbcparser.MID, "MXD#"},
+	{cbcparser.MIDPercent, "MXD%"},
+	{cbcparser.GRA, "NEUT#"},
+	{cbcparser.GRAPercent, "NEUT%"},
+	{cbcparser.RBC, "RBC"},
+	{cbcparser.HGB, "HGB"},
+	{cbcparser.HCT, "HCT"},
+	{cbcparser.MCV, "MCV"},
+	{cbcparser.MCH, "MCH"},
+	{cbcparser.MCHC, "MCHC"},
+	{cbcparser.RDWc, "RDW_CV"},
+	{cbcparser.RDWs, "RDW_SD"},
+	{cbcparser.PLT, "PLT"},
+	{cbcparser.PDW, "PDW"},
+	{cbcparser.MPV, "MPV"},
+	{cbcparser.PCT, "PCT"},
+	{cbcparser.PLCR, "P_LCR"},
+	{cbcparser.PLCC, "P_LCC"},
+}
+
+// Parameters returns the parameters reported by the machine, e.g to require
+// their ranges with cbcparser.CBCNormalRange.Validate.
+func Parameters() []cbcparser.Parameter {
+	params := make([]cbcparser.Parameter, len(analytes))
+	for i, a := range analytes {
+		params[i] = a.param
+	}
+	return params
+}
+
+// required_columns returns the labels of all columns the parser reads.
+func required_columns() []string {
+	labels := []string{colSampleID, colMode, colAnalysisTime}
+	for _, a := range analytes {
+		labels = append(labels, a.label)
 	}
-	return float32(fvalue)
+	return labels
 }
 
-// Extracts units from header, replacing invalid unicode
-// with μ(micro) symbol.
+// Extracts units from header e.g 10^3/µL from WBC(10^3/µL).
+// The micro sign is written in GB2312 by the machine and decoded
+// when the file is read.
 func extract_units(value string) string {
-	rs := UnitsRegex.FindStringSubmatch(strings.ToValidUTF8(value, "μ"))
+	rs := UnitsRegex.FindStringSubmatch(value)
 
 	if len(rs) == 2 {
 		return rs[1]
@@ -48,17 +92,85 @@ func extract_units(value string) string {
 	return ""
 }
 
-// Returns L or H if value is out of range or an empty string.
-func get_flag(value float32, nrange cbcparser.NormalRange) string {
-	if value < nrange.Lower {
-		return "L"
+// Units of the columns exported with empty brackets e.g "MXD#()".
+var default_units = map[string]string{
+	"MXD#":  "10^3/µL",
+	"MXD%":  "%",
+	"NEUT#": "10^3/µL",
+	"NEUT%": "%",
+}
+
+// split_header separates a header e.g "HGB(g/dL)" into its label and units.
+func split_header(header string) (string, string) {
+	if i := strings.IndexByte(header, '('); i > 0 {
+		label, units := header[:i], extract_units(header)
+		if units == "" {
+			units = default_units[label]
+		}
+		return label, units
 	}
+	return header, ""
+}
 
-	if value > nrange.Upper {
-		return "H"
+// validate_units checks that the header of every analyte declares
+// a unit recognised for its parameter.
+func validate_units(cols *cbcparser.Columns) error {
+	for _, a := range analytes {
+		if _, err := units.ParseFor(a.param, cols.Units(a.label)); err != nil {
+			return &cbcparser.ParseError{Field: a.label, Value: cols.Units(a.label), Err: err}
+		}
 	}
+	return nil
+}
 
-	return ""
+// DecodeFlag decodes a flag code printed or sent to the LIS by the Edan machine.
+// The csv export carries no flags, so the parser computes them from the normal ranges.
+//
+//	L, ↓  below the normal range
+//	H, ↑  above the normal range
+//	?     the value is suspect
+//
+// Unknown codes are treated as suspect.
+func DecodeFlag(code string) cbcparser.Flag {
+	switch strings.TrimSpace(code) {
+	case "":
+		return cbcparser.FlagNone
+	case "L", "↓":
+		return cbcparser.FlagLow
+	case "H", "↑":
+		return cbcparser.FlagHigh
+	default:
+		return cbcparser.FlagSuspect
+	}
+}
+
+func init() {
+	cbcparser.Register("edan", Detect, NewMultiParser())
+}
+
+// Detect reports whether header is the header line of an Edan export.
+func Detect(header string) bool {
+	return strings.Contains(header, colSampleID) &&
+		strings.Contains(header, colMode) &&
+		strings.Contains(header, colAnalysisTime)
+}
+
+// Classify returns the kind of run from the Mode column.
+// Sample IDs are chosen by the lab and never classify a record.
+func Classify(mode string) cbcparser.RecordType {
+	mode = strings.ToUpper(mode)
+
+	switch {
+	case strings.Contains(mode, "BACKGROUND"), strings.Contains(mode, "BLANK"):
+		return cbcparser.RecordBlank
+	case strings.Contains(mode, "QC"):
+		return cbcparser.RecordControl
+	case strings.Contains(mode, "CAL"):
+		return cbcparser.RecordCalibration
+	case strings.Contains(mode, "STARTUP"):
+		return cbcparser.RecordStartup
+	}
+	return cbcparser.RecordPatient
 }
 
 // Initialize a new CSV Parser
@@ -93,266 +205,85 @@ func (cbc EdanCBCResult) Write(out io.Writer, format cbcparser.OutFormat) error
 	return err
 }
 
-// ParseTSV reads from r and parses the data into an slice of CBCResult structs.
+// Parse reads from r and parses the first record into a result.
 // The text file is expected to be in the csv format.
 // The first line of the file is expected to be the header.
 //
-// The header is expected to be in the following format(with 24 columns):
-func (cbc EdanCBCResult) Parse(r io.Reader, normal_ranges *cbcparser.CBCNormalRange) (cbcparser.CBCWriter, error) {
-	reader := csv.NewReader(r)
-	reader.Comma = separator // ',' or '\t'
-	reader.FieldsPerRecord = nfields
-
-	data, err := reader.ReadAll()
-
-	if err != nil {
-		return nil, cbcparser.ErrInvalidCSV
-	}
-
-	if len(data) < 2 {
-		return nil, cbcparser.ErrInsufficientRows
-	}
-
-	// initialize with numrows excluding header row
-	headers := data[0]
-	row := data[1]
-	result := set_cbc_value(headers, row, normal_ranges)
-	return result, nil
+// Columns are located by their header, so extra or reordered columns are tolerated.
+// The header is expected to contain the following columns:
+//
+// Sample ID,Mode,Analysis Time,WBC(10^3/µL),LYM#(10^3/µL),LYM%(%),MXD#(),MXD%(),NEUT#(),NEUT%(),RBC(10^6/µL),HGB(g/dL),HCT(%),MCV(fL),MCH(pg),MCHC(g/dL),RDW_CV(%),RDW_SD(fL),PLT(10^3/µL),PDW(fL),MPV(fL),PCT(%),P_LCR(%),P_LCC(10^3/µL)
+func (cbc EdanCBCResult) Parse(r io.Reader, normal_ranges *cbcparser.CBCNormalRange, opts ...cbcparser.Option) (*cbcparser.Result, error) {
+	return NewReader(r, normal_ranges, opts...).rows.First()
 }
 
-// MultiParse reads from r and parses the data into an slice of a CBCWriter struct.
-func (EdanCBCResultMulti) ParseMulti(r io.Reader, normal_ranges *cbcparser.CBCNormalRange) (cbcparser.CBCMultiWriter, error) {
-	reader := csv.NewReader(r)
-	reader.Comma = separator // ',' or '\t'
-	reader.FieldsPerRecord = nfields
-
-	data, err := reader.ReadAll()
-
-	if err != nil {
-		return nil, cbcparser.ErrInvalidCSV
-	}
-
-	if len(data) < 2 {
-		return nil, cbcparser.ErrInsufficientRows
-	}
-
-	headers := data[0]
-	var result []cbcparser.CBCWriter
-	for i := 1; i < len(data); i++ {
-		row := data[i]
-		result = append(result, set_cbc_value(headers, row, normal_ranges))
-	}
-
-	return result, nil
+// MultiParse reads from r and parses the data into a slice of results.
+// Use NewReader to process large files without loading them into memory.
+// With WithSkipBadRows, the good records are returned together with cbcparser.RowErrors.
+func (EdanCBCResultMulti) ParseMulti(r io.Reader, normal_ranges *cbcparser.CBCNormalRange, opts ...cbcparser.Option) (cbcparser.Results, error) {
+	return NewReader(r, normal_ranges, opts...).rows.ReadAll()
 }
 
-/*
-row index are as below.
--------------------------
-Indexes: 0 - 4 are identifiers
-Indexes: 5 - 48 have units and flags
-Index: 49 (Type)
-Index: 50 (Warning)
-*/
-func set_cbc_value(headers []string, row []string, normal_ranges *cbcparser.CBCNormalRange) cbcparser.CBCWriter {
+// set_cbc_value builds a record from row using the columns found in the header.
+// Flags are computed from the normal ranges since the machine does not export them.
+func (rd *Reader) set_cbc_value(row []string) (EdanCBCResult, error) {
+	cols := rd.rows.Columns
+	normal_ranges := rd.normal_ranges
+
 	cbcRes := EdanCBCResult{}
 	// Patient Identifiers
-	cbcRes.SID = row[0]
-	cbcRes.Mode = row[1]
-	cbcRes.AnalysisTime = row[2]
-
-	// Absoluet counts
-	wbc := parse_float(row[3])
-	cbcRes.WBC = cbcparser.CBCValue{
-		Value: wbc,
-		Units: extract_units(headers[3]),
-	}
-
-	lym := parse_float(row[4])
-	cbcRes.LYM = cbcparser.CBCValue{
-		Value: lym,
-		Units: extract_units(headers[4]),
-	}
-
-	lym_percent := parse_float(row[5])
-	cbcRes.LYMPercent = cbcparser.CBCValue{
-		Value: lym_percent,
-		Units: extract_units(headers[5]),
-	}
-
-	mid := parse_float(row[6])
-	cbcRes.MID = cbcparser.CBCValue{
-		Value: mid,
-		Units: extract_units(headers[6]),
-	}
-
-	mid_percent := parse_float(row[7])
-	cbcRes.MIDPercent = cbcparser.CBCValue{
-		Value: mid_percent,
-		Units: extract_units(headers[7]),
-	}
-
-	granulocytes := parse_float(row[8])
-	cbcRes.GRA = cbcparser.CBCValue{
-		Value: granulocytes,
-		Units: extract_units(headers[8]),
-	}
-
-	gra_percent := parse_float(row[9])
-	cbcRes.GRAPercent = cbcparser.CBCValue{
-		Value: gra_percent,
-		Units: extract_units(headers[9]),
-	}
-
-	rbc := parse_float(row[10])
-	cbcRes.RBC = cbcparser.CBCValue{
-		Value: rbc,
-		Units: extract_units(headers[10]),
-	}
-
-	hgb := parse_float(row[11])
-	cbcRes.HGB = cbcparser.CBCValue{
-		Value: hgb,
-		Units: extract_units(headers[11]),
-	}
-
-	hct := parse_float(row[12])
-	cbcRes.HCT = cbcparser.CBCValue{
-		Value: hct,
-		Units: extract_units(headers[12]),
-	}
-
-	mcv := parse_float(row[13])
-	cbcRes.MCV = cbcparser.CBCValue{
-		Value: mcv,
-		Units: extract_units(headers[13]),
-	}
-
-	mch := parse_float(row[14])
-	cbcRes.MCH = cbcparser.CBCValue{
-		Value: mch,
-		Units: extract_units(headers[14]),
-	}
-
-	mchc := parse_float(row[15])
-	cbcRes.MCHC = cbcparser.CBCValue{
-		Value: mchc,
-		Units: extract_units(headers[15]),
-	}
-
-	rdwc := parse_float(row[16])
-	cbcRes.RDWc = cbcparser.CBCValue{
-		Value: rdwc,
-		Units: extract_units(headers[16]),
-	}
-
-	rdws := parse_float(row[17])
-	cbcRes.RDWs = cbcparser.CBCValue{
-		Value: rdws,
-		Units: extract_units(headers[17]),
-	}
-
-	plt := parse_float(row[18])
-	cbcRes.PLT = cbcparser.CBCValue{
-		Value: plt,
-		Units: extract_units(headers[18]),
-	}
-
-	pdw := parse_float(row[19])
-	cbcRes.PDW = cbcparser.CBCValue{
-		Value: pdw,
-		Units: extract_units(headers[19]),
-	}
+	cbcRes.SID = cols.Get(row, colSampleID)
+	cbcRes.Mode = cols.Get(row, colMode)
+	cbcRes.AnalysisMode = ParseMode(cbcRes.Mode)
+	cbcRes.RecordType = rd.classify(row)
+	cbcRes.AnalysisTime = cols.Get(row, colAnalysisTime)
 
-	mpv := parse_float(row[20])
-	cbcRes.MPV = cbcparser.CBCValue{
-		Value: mpv,
-		Units: extract_units(headers[20]),
+	// e.g 11/06/2022 08:52
+	date, clock := strings.TrimSpace(cbcRes.AnalysisTime), ""
+	if i := strings.LastIndexByte(date, ' '); i > 0 {
+		date, clock = date[:i], date[i+1:]
 	}
 
-	pct := parse_float(row[21])
-	cbcRes.PCT = cbcparser.CBCValue{
-		Value: pct,
-		Units: extract_units(headers[21]),
-	}
-
-	plcc := parse_float(row[22])
-	cbcRes.PLCC = cbcparser.CBCValue{
-		Value: plcc,
-		Units: extract_units(headers[22]),
-	}
-
-	plcr := parse_float(row[23])
-	cbcRes.PLCR = cbcparser.CBCValue{
-		Value: plcr,
-		Units: extract_units(headers[23]),
+	timestamp, err := rd.rows.Options.ParseDateTime(date, clock)
+	if err != nil {
+		return cbcRes, rd.rows.FieldError(row, colAnalysisTime, err)
 	}
+	cbcRes.Timestamp = timestamp
 
-	// Set normal ranges if available for each CBC value
-	if normal_ranges != nil {
-		cbcRes.WBC.Flag = get_flag(wbc, normal_ranges.WBC)
-		cbcRes.WBC.NormalRange = normal_ranges.WBC
-
-		cbcRes.LYM.Flag = get_flag(lym, normal_ranges.LYM)
-		cbcRes.LYM.NormalRange = normal_ranges.LYM
-
-		cbcRes.LYMPercent.Flag = get_flag(lym_percent, normal_ranges.LYMPercent)
-		cbcRes.LYMPercent.NormalRange = normal_ranges.LYMPercent
-
-		cbcRes.MID.Flag = get_flag(mid, normal_ranges.MID)
-		cbcRes.MID.NormalRange = normal_ranges.MID
-
-		cbcRes.MIDPercent.Flag = get_flag(mid_percent, normal_ranges.MIDPercent)
-		cbcRes.MIDPercent.NormalRange = normal_ranges.MIDPercent
-
-		cbcRes.GRA.Flag = get_flag(granulocytes, normal_ranges.GRA)
-		cbcRes.GRA.NormalRange = normal_ranges.GRA
-
-		cbcRes.GRAPercent.Flag = get_flag(gra_percent, normal_ranges.GRAPercent)
-		cbcRes.GRAPercent.NormalRange = normal_ranges.GRAPercent
-
-		cbcRes.RBC.Flag = get_flag(rbc, normal_ranges.RBC)
-		cbcRes.RBC.NormalRange = normal_ranges.RBC
+	values := cbcRes.values()
+	for _, a := range analytes {
+		cell := cols.Get(row, a.label)
+		value, status := rd.rows.Options.ParseValue(cell)
 
-		cbcRes.HGB.Flag = get_flag(hgb, normal_ranges.HGB)
-		cbcRes.HGB.NormalRange = normal_ranges.HGB
+		unit, _ := units.Parse(cols.Units(a.label))
 
-		cbcRes.HCT.Flag = get_flag(hct, normal_ranges.HCT)
-		cbcRes.HCT.NormalRange = normal_ranges.HCT
-
-		cbcRes.MCV.Flag = get_flag(mcv, normal_ranges.MCV)
-		cbcRes.MCV.NormalRange = normal_ranges.MCV
-
-		cbcRes.MCH.Flag = get_flag(mch, normal_ranges.MCH)
-		cbcRes.MCH.NormalRange = normal_ranges.MCH
-
-		cbcRes.MCHC.Flag = get_flag(mchc, normal_ranges.MCHC)
-		cbcRes.MCHC.NormalRange = normal_ranges.MCHC
-
-		cbcRes.RDWc.Flag = get_flag(rdwc, normal_ranges.RDWc)
-		cbcRes.RDWc.NormalRange = normal_ranges.RDWc
-
-		cbcRes.RDWs.Flag = get_flag(rdws, normal_ranges.RDWs)
-		cbcRes.RDWs.NormalRange = normal_ranges.RDWs
-
-		cbcRes.PLT.Flag = get_flag(plt, normal_ranges.PLT)
-		cbcRes.PLT.NormalRange = normal_ranges.PLT
-
-		cbcRes.PDW.Flag = get_flag(pdw, normal_ranges.PDWc)
-		cbcRes.PDW.NormalRange = normal_ranges.PDW
+		v := values[a.param]
+		*v = cbcparser.CBCValue{
+			Value:  value,
+			Status: status,
+			Units:  cols.Units(a.label),
+			UCUM:   unit.UCUM,
+			LOINC:  a.param.LOINC(),
+		}
 
-		cbcRes.MPV.Flag = get_flag(mpv, normal_ranges.MPV)
-		cbcRes.MPV.NormalRange = normal_ranges.MPV
+		if status != cbcparser.StatusOK {
+			v.Raw = cell
+		}
 
-		cbcRes.PCT.Flag = get_flag(pct, normal_ranges.PCT)
-		cbcRes.PCT.NormalRange = normal_ranges.PCT
+		// Set normal ranges if available for each CBC value.
+		// Parameters without a range are left unflagged.
+		if normal_ranges == nil {
+			continue
+		}
 
-		cbcRes.PLCC.Flag = get_flag(plcc, normal_ranges.PLCC)
-		cbcRes.PLCC.NormalRange = normal_ranges.PLCC
+		if nr := normal_ranges.Range(a.param); !nr.IsZero() {
+			v.NormalRange = nr
 
-		cbcRes.PLCR.Flag = get_flag(plcr, normal_ranges.PLCR)
-		cbcRes.PLCR.NormalRange = normal_ranges.PLCR
+			// Absent values are never flagged.
+			if v.Valid() {
+				v.Flag = cbcparser.FlagFor(v.Value, nr)
+			}
+		}
 	}
-
-	return cbcRes
+	return cbcRes, nil
 }
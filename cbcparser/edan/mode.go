@@ -0,0 +1,57 @@
+package edan
+
+import (
+	"strings"
+
+	"github.com/abiiranathan/cbcparser/cbcparser"
+)
+
+// Test panels of the Edan machine.
+const (
+	PanelCBC     = "CBC"
+	PanelCBCDiff = "CBC+DIFF"
+)
+
+// Tube modes of the Edan machine.
+const (
+	TubeOpen   = "open"
+	TubeClosed = "closed"
+)
+
+// Mode is the decoded Mode column e.g "CBC-WB-Open".
+// Fields are empty when the corresponding part of the mode is not recognised.
+type Mode struct {
+	Panel      string               `json:"panel"`
+	SampleType cbcparser.SampleType `json:"sample_type"`
+	Tube       string               `json:"tube"`
+}
+
+// ParseMode decodes a mode such as "CBC-WB-Open" or "CBC+DIFF-PD-Closed".
+// The parts are matched case-insensitively and in any order.
+func ParseMode(mode string) Mode {
+	var m Mode
+	for _, part := range strings.Split(strings.TrimSpace(mode), "-") {
+		switch strings.ToUpper(strings.TrimSpace(part)) {
+		case "CBC":
+			m.Panel = PanelCBC
+		case "CBC+DIFF", "CBC+5DIFF", "DIFF":
+			m.Panel = PanelCBCDiff
+		case "WB":
+			m.SampleType = cbcparser.WholeBlood
+		case "PD", "PB", "PRE":
+			m.SampleType = cbcparser.Prediluted
+		case "CP", "CAP":
+			m.SampleType = cbcparser.Capillary
+		case "OPEN", "OV":
+			m.Tube = TubeOpen
+		case "CLOSED", "CV", "AUTO":
+			m.Tube = TubeClosed
+		}
+	}
+	return m
+}
+
+// Prediluted reports whether the sample was prediluted before analysis.
+func (m Mode) Prediluted() bool {
+	return m.SampleType == cbcparser.Prediluted
+}
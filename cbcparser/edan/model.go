@@ -1,8 +1,12 @@
 package edan
 
-import "github.com/abiiranathan/cbcparser/cbcparser"
+import (
+	"time"
 
-// Sample ID,Mode,Analysis Time,WBC(10^3/��L),LYM#(10^3/��L),LYM%(%),MXD#(),MXD%(),NEUT#(),NEUT%(),RBC(10^6/��L),HGB(g/dL),HCT(%),MCV(fL),MCH(pg),MCHC(g/dL),RDW_CV(%),RDW_SD(fL),PLT(10^3/��L),PDW(fL),MPV(fL),PCT(%),P_LCR(%),P_LCC(10^3/��L)
+	"github.com/abiiranathan/cbcparser/cbcparser"
+)
+
+// Sample ID,Mode,Analysis Time,WBC(10^3/µL),LYM#(10^3/µL),LYM%(%),MXD#(),MXD%(),NEUT#(),NEUT%(),RBC(10^6/µL),HGB(g/dL),HCT(%),MCV(fL),MCH(pg),MCHC(g/dL),RDW_CV(%),RDW_SD(fL),PLT(10^3/µL),PDW(fL),MPV(fL),PCT(%),P_LCR(%),P_LCC(10^3/µL)
 // Structure to store data parsed from the text file
 // exported by the Edan Pro30 CBC Machine.
 type EdanCBCResult struct {
@@ -11,6 +15,15 @@ type EdanCBCResult struct {
 	AnalysisTime string `json:"analysis_time"`
 	PID          string `json:"pid"`
 
+	// Mode decoded into panel, sample type and tube.
+	AnalysisMode Mode `json:"analysis_mode"`
+
+	// Kind of run, derived from the Sample ID and Mode columns.
+	RecordType cbcparser.RecordType `json:"record_type"`
+
+	// AnalysisTime parsed in the lab's time zone.
+	Timestamp *time.Time `json:"timestamp"`
+
 	WBC        cbcparser.CBCValue `json:"wbc"`
 	LYM        cbcparser.CBCValue `json:"lym"`
 	LYMPercent cbcparser.CBCValue `json:"lym_percent"`
@@ -38,3 +51,53 @@ type EdanCBCResult struct {
 }
 
 type EdanCBCResultMulti []EdanCBCResult
+
+// Instrument identifies the Edan Pro 30.
+var Instrument = cbcparser.Instrument{
+	Manufacturer: "Edan Instruments",
+	Model:        "Edan Pro 30",
+}
+
+// values returns pointers to the analytes of cbc keyed by parameter.
+func (cbc *EdanCBCResult) values() map[cbcparser.Parameter]*cbcparser.CBCValue {
+	return map[cbcparser.Parameter]*cbcparser.CBCValue{
+		cbcparser.WBC:        &cbc.WBC,
+		cbcparser.LYM:        &cbc.LYM,
+		cbcparser.LYMPercent: &cbc.LYMPercent,
+		cbcparser.MID:        &cbc.MID,
+		cbcparser.MIDPercent: &cbc.MIDPercent,
+		cbcparser.GRA:        &cbc.GRA,
+		cbcparser.GRAPercent: &cbc.GRAPercent,
+		cbcparser.RBC:        &cbc.RBC,
+		cbcparser.HGB:        &cbc.HGB,
+		cbcparser.HCT:        &cbc.HCT,
+		cbcparser.MCV:        &cbc.MCV,
+		cbcparser.MCH:        &cbc.MCH,
+		cbcparser.MCHC:       &cbc.MCHC,
+		cbcparser.RDWc:       &cbc.RDWc,
+		cbcparser.RDWs:       &cbc.RDWs,
+		cbcparser.PLT:        &cbc.PLT,
+		cbcparser.PDW:        &cbc.PDW,
+		cbcparser.MPV:        &cbc.MPV,
+		cbcparser.PCT:        &cbc.PCT,
+		cbcparser.PLCC:       &cbc.PLCC,
+		cbcparser.PLCR:       &cbc.PLCR,
+	}
+}
+
+// Result converts cbc into the machine-neutral result.
+// cbc is kept as the raw view of the result.
+func (cbc EdanCBCResult) Result() *cbcparser.Result {
+	res := cbcparser.NewResult(Instrument)
+	res.SampleID = cbc.SID
+	res.PatientID = cbc.PID
+	res.Timestamp = cbc.Timestamp
+	res.SampleType = cbc.AnalysisMode.SampleType
+	res.RecordType = cbc.RecordType
+	res.Raw = cbc
+
+	for p, v := range cbc.values() {
+		res.Set(p, *v)
+	}
+	return res
+}
@@ -0,0 +1,216 @@
+package cbcparser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding is the character encoding of a machine export.
+type Encoding string
+
+const (
+	UTF8        Encoding = "utf-8"
+	UTF16LE     Encoding = "utf-16le"
+	UTF16BE     Encoding = "utf-16be"
+	Windows1252 Encoding = "windows-1252" // also used for Latin-1
+	// Chinese PC software(e.g Edan) writes µ as the GB2312 sequence A6 CC.
+	// Only ASCII and µ are decoded, other characters become U+FFFD.
+	GB2312 Encoding = "gb2312"
+)
+
+// MicroSign is the character used for the micro prefix in decoded units.
+const MicroSign = 'µ' // U+00B5
+
+// sniffSize is the number of bytes inspected to detect the encoding.
+const sniffSize = 4096
+
+// DetectEncoding inspects the first bytes of an export and returns its encoding
+// and the length of its byte order mark.
+func DetectEncoding(data []byte) (Encoding, int) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return UTF8, 3
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return UTF16LE, 2
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return UTF16BE, 2
+	}
+
+	// UTF-16 without BOM: ASCII text has a zero in every other byte.
+	var even, odd int
+	for i := 0; i+1 < len(data); i += 2 {
+		if data[i] == 0 {
+			even++
+		}
+		if data[i+1] == 0 {
+			odd++
+		}
+	}
+
+	pairs := len(data) / 2
+	switch {
+	case pairs > 0 && odd*3 > pairs && even == 0:
+		return UTF16LE, 0
+	case pairs > 0 && even*3 > pairs && odd == 0:
+		return UTF16BE, 0
+	case valid_utf8(data):
+		return UTF8, 0
+	case bytes.Contains(data, []byte{0xA6, 0xCC}):
+		return GB2312, 0
+	}
+	return Windows1252, 0
+}
+
+// valid_utf8 is like utf8.Valid but accepts a rune cut at the end of data.
+func valid_utf8(data []byte) bool {
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && size == 1 {
+			return len(data) < utf8.UTFMax && !utf8.FullRune(data)
+		}
+		data = data[size:]
+	}
+	return true
+}
+
+// NewUTF8Reader detects the encoding of r and returns a reader that
+// transcodes it to UTF-8 without a byte order mark.
+// If enc is not empty, it is used instead of the detected encoding.
+func NewUTF8Reader(r io.Reader, enc Encoding) (io.Reader, Encoding, error) {
+	br := bufio.NewReaderSize(r, sniffSize)
+	data, err := br.Peek(sniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, "", err
+	}
+
+	detected, bom := DetectEncoding(data)
+	if enc == "" || enc == detected {
+		enc = detected
+		br.Discard(bom)
+	}
+
+	switch enc {
+	case UTF16LE:
+		return &decoder{r: br, decode: utf16_decoder(binary.LittleEndian)}, enc, nil
+	case UTF16BE:
+		return &decoder{r: br, decode: utf16_decoder(binary.BigEndian)}, enc, nil
+	case Windows1252:
+		return &decoder{r: br, decode: decode_cp1252}, enc, nil
+	case GB2312:
+		return &decoder{r: br, decode: decode_gb2312}, enc, nil
+	}
+	return br, enc, nil
+}
+
+// WithEncoding sets the encoding of the export instead of detecting it.
+func WithEncoding(enc Encoding) Option {
+	return func(o *Options) {
+		o.Encoding = enc
+	}
+}
+
+// decoder transcodes runes read by decode to UTF-8.
+type decoder struct {
+	r       *bufio.Reader
+	decode  func(r *bufio.Reader) (rune, error)
+	pending []byte
+	err     error
+}
+
+func (d *decoder) Read(p []byte) (int, error) {
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+
+	var buf [utf8.UTFMax]byte
+	for n < len(p) && d.err == nil {
+		r, err := d.decode(d.r)
+		if err != nil {
+			d.err = err
+			break
+		}
+
+		size := utf8.EncodeRune(buf[:], r)
+		copied := copy(p[n:], buf[:size])
+		d.pending = append(d.pending, buf[copied:size]...)
+		n += copied
+	}
+
+	if n > 0 {
+		return n, nil
+	}
+	return 0, d.err
+}
+
+func utf16_decoder(order binary.ByteOrder) func(r *bufio.Reader) (rune, error) {
+	read := func(r *bufio.Reader) (uint16, error) {
+		var b [2]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return utf8.RuneError, nil
+			}
+			return 0, err
+		}
+		return order.Uint16(b[:]), nil
+	}
+
+	return func(r *bufio.Reader) (rune, error) {
+		c, err := read(r)
+		if err != nil {
+			return 0, err
+		}
+
+		if !utf16.IsSurrogate(rune(c)) {
+			return rune(c), nil
+		}
+
+		c2, err := read(r)
+		if err != nil {
+			return utf8.RuneError, nil
+		}
+		return utf16.DecodeRune(rune(c), rune(c2)), nil
+	}
+}
+
+// Characters of Windows-1252 in the range 0x80 - 0x9F.
+// The remaining bytes are the same as Latin-1.
+var cp1252 = [32]rune{
+	'€', '�', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '�', 'Ž', '�',
+	'�', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '�', 'ž', 'Ÿ',
+}
+
+func decode_cp1252(r *bufio.Reader) (rune, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	if b >= 0x80 && b <= 0x9F {
+		return cp1252[b-0x80], nil
+	}
+	return rune(b), nil // 0xB5 is µ
+}
+
+func decode_gb2312(r *bufio.Reader) (rune, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	if b < 0x80 {
+		return rune(b), nil
+	}
+
+	b2, err := r.ReadByte()
+	if err != nil {
+		return utf8.RuneError, nil
+	}
+
+	if b == 0xA6 && b2 == 0xCC {
+		return MicroSign, nil
+	}
+	return utf8.RuneError, nil
+}
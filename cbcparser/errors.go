@@ -0,0 +1,89 @@
+package cbcparser
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strings"
+)
+
+// ParseError describes a row or value that could not be parsed.
+// It wraps the underlying error(e.g *csv.ParseError or *strconv.NumError).
+//
+// errors.Is(err, ErrInvalidCSV) reports true for every ParseError.
+type ParseError struct {
+	File   string // Name of the file, if known
+	Line   int    // Line where the error occurred(1-based)
+	Column int    // Column where the error occurred(1-based byte index)
+	Field  string // Header label of the field, if known
+	Value  string // Raw value of the field, if known
+	Err    error  // The underlying error
+}
+
+// NewParseError converts an error returned by a csv.Reader into a *ParseError.
+func NewParseError(file string, err error) *ParseError {
+	pe := &ParseError{File: file, Err: err}
+	if csvErr, ok := err.(*csv.ParseError); ok {
+		pe.Line = csvErr.Line
+		pe.Column = csvErr.Column
+	}
+	return pe
+}
+
+// NewFieldError returns a *ParseError for field i of the record most
+// recently read by reader.
+func NewFieldError(file string, reader *csv.Reader, i int, field, value string, err error) *ParseError {
+	line, column := reader.FieldPos(i)
+	return &ParseError{
+		File:   file,
+		Line:   line,
+		Column: column,
+		Field:  field,
+		Value:  value,
+		Err:    err,
+	}
+}
+
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File)
+		b.WriteString(":")
+	}
+
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "%d:%d: ", e.Line, e.Column)
+	} else if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+
+	if e.Field != "" {
+		fmt.Fprintf(&b, "field %q value %q: ", e.Field, e.Value)
+	}
+
+	// csv.ParseError already reports the line and column.
+	if csvErr, ok := e.Err.(*csv.ParseError); ok {
+		b.WriteString(csvErr.Err.Error())
+	} else {
+		b.WriteString(e.Err.Error())
+	}
+	return b.String()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ParseError) Is(target error) bool {
+	return target == ErrInvalidCSV
+}
+
+// RowErrors is returned together with the successfully parsed records when
+// parsing with SkipBadRows. It lists the rows that were skipped.
+type RowErrors []*ParseError
+
+func (errs RowErrors) Error() string {
+	if len(errs) == 1 {
+		return errs[0].Error()
+	}
+	return fmt.Sprintf("%d rows could not be parsed, first error: %s", len(errs), errs[0])
+}
@@ -0,0 +1,91 @@
+package cbcparser
+
+import "fmt"
+
+// Flag classifies a value against its range or reports a measurement problem.
+type Flag int
+
+const (
+	// Within the normal range or not flagged by the machine.
+	FlagNone Flag = iota
+	// Below the normal range.
+	FlagLow
+	// Above the normal range.
+	FlagHigh
+	// Below the critical(panic) limit.
+	FlagCriticalLow
+	// Above the critical(panic) limit.
+	FlagCriticalHigh
+	// The machine could not measure the value reliably(e.g HumaCount "E").
+	// The value must not be reported.
+	FlagInvalid
+	// The value is questionable and should be reviewed before reporting.
+	FlagSuspect
+)
+
+var flagNames = [...]string{
+	FlagNone:         "none",
+	FlagLow:          "low",
+	FlagHigh:         "high",
+	FlagCriticalLow:  "critical_low",
+	FlagCriticalHigh: "critical_high",
+	FlagInvalid:      "invalid",
+	FlagSuspect:      "suspect",
+}
+
+func (f Flag) String() string {
+	if f < 0 || int(f) >= len(flagNames) {
+		return fmt.Sprintf("Flag(%d)", int(f))
+	}
+	return flagNames[f]
+}
+
+func (f Flag) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+func (f *Flag) UnmarshalText(text []byte) error {
+	for i, name := range flagNames {
+		if name == string(text) {
+			*f = Flag(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown flag %q", text)
+}
+
+// IsAbnormal reports whether f marks a value outside its range.
+func (f Flag) IsAbnormal() bool {
+	switch f {
+	case FlagLow, FlagHigh, FlagCriticalLow, FlagCriticalHigh:
+		return true
+	}
+	return false
+}
+
+// IsCritical reports whether f marks a value beyond a critical limit.
+func (f Flag) IsCritical() bool {
+	return f == FlagCriticalLow || f == FlagCriticalHigh
+}
+
+// FlagFor returns FlagCriticalLow or FlagCriticalHigh if value is beyond the
+// critical limits of nrange, FlagLow or FlagHigh if value is out of range or FlagNone.
+func FlagFor(value float32, nrange NormalRange) Flag {
+	if nrange.CriticalLower != 0 && value < nrange.CriticalLower {
+		return FlagCriticalLow
+	}
+
+	if nrange.CriticalUpper != 0 && value > nrange.CriticalUpper {
+		return FlagCriticalHigh
+	}
+
+	if value < nrange.Lower {
+		return FlagLow
+	}
+
+	if value > nrange.Upper {
+		return FlagHigh
+	}
+
+	return FlagNone
+}
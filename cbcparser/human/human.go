@@ -9,26 +9,79 @@ package human
 //
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"io"
-	"strconv"
 	"strings"
 
 	"github.com/abiiranathan/cbcparser/cbcparser"
+	"github.com/abiiranathan/cbcparser/cbcparser/units"
 )
 
+// Default field separator, see cbcparser.WithFieldSeparator.
+const separator = '\t'
+
+// Labels of the identifier columns.
 const (
-	nfields   = 52
-	separator = '\t'
+	colSampleID  = "Sample ID"
+	colDate      = "Date"
+	colTime      = "Time"
+	colPatientID = "Patient ID"
+	colBirthDate = "Birth date"
+	colType      = "Type"
+	colWarning   = "Warning"
 )
 
-func parse_float(value string) float32 {
-	fvalue, err := strconv.ParseFloat(value, 32)
-	if err != nil {
-		return 0.0
+// Analyte columns keyed by the label used in the header.
+// Each analyte has a value column e.g "HGB g/dl" and a flag column e.g "HGB flag".
+var analytes = []struct {
+	param cbcparser.Parameter
+	label string
+}{
+	{cbcparser.WBC, "WBC"},
+	{cbcparser.LYM, "LYM"},
+	{cbcparser.MID, "MID"},
+	{cbcparser.GRA, "GRA"},
+	{cbcparser.LYMPercent, "LYM%"},
+	{cbcparser.MIDPercent, "MID%"},
+	{cbcparser.GRAPercent, "GRA%"},
+	{cbcparser.RBC, "RBC"},
+	{cbcparser.HGB, "HGB"},
+	{cbcparser.HCT, "HCT"},
+	{cbcparser.MCV, "MCV"},
+	{cbcparser.MCH, "MCH"},
+	{cbcparser.MCHC, "MCHC"},
+	{cbcparser.RDWs, "RDWs"},
+	{cbcparser.RDWc, "RDWc"},
+	{cbcparser.PLT, "PLT"},
+	{cbcparser.PCT, "PCT"},
+	{cbcparser.MPV, "MPV"},
+	{cbcparser.PDWs, "PDWs"},
+	{cbcparser.PDWc, "PDWc"},
+	{cbcparser.PLCC, "P-LCC"},
+	{cbcparser.PLCR, "P-LCR"},
+}
+
+// Parameters returns the parameters reported by the machine, e.g to require
+// their ranges with cbcparser.CBCNormalRange.Validate.
+func Parameters() []cbcparser.Parameter {
+	params := make([]cbcparser.Parameter, len(analytes))
+	for i, a := range analytes {
+		params[i] = a.param
+	}
+	return params
+}
+
+// required_columns returns the labels of all columns the parser reads.
+func required_columns() []string {
+	labels := []string{colSampleID, colDate, colTime, colPatientID, colBirthDate}
+	for _, a := range analytes {
+		labels = append(labels, a.label, flag_label(a.label))
 	}
-	return float32(fvalue)
+	return append(labels, colType, colWarning)
+}
+
+func flag_label(label string) string {
+	return label + " flag"
 }
 
 func extract_units(value string) string {
@@ -39,6 +92,94 @@ func extract_units(value string) string {
 	return valarr[1]
 }
 
+// split_header separates an analyte header e.g "HGB g/dl" into its label and units.
+// Flag and identifier headers are returned unchanged.
+func split_header(header string) (string, string) {
+	if strings.HasSuffix(header, " flag") {
+		return header, ""
+	}
+
+	units := extract_units(header)
+	label := strings.TrimSuffix(header, " "+units)
+
+	for _, a := range analytes {
+		if strings.EqualFold(a.label, label) {
+			return label, units
+		}
+	}
+	return header, ""
+}
+
+// validate_units checks that the header of every analyte declares
+// a unit recognised for its parameter.
+func validate_units(cols *cbcparser.Columns) error {
+	for _, a := range analytes {
+		if _, err := units.ParseFor(a.param, cols.Units(a.label)); err != nil {
+			return &cbcparser.ParseError{Field: a.label, Value: cols.Units(a.label), Err: err}
+		}
+	}
+	return nil
+}
+
+func init() {
+	cbcparser.Register("human", Detect, NewMultiParser())
+}
+
+// Detect reports whether header is the header line of a HumaCount export.
+func Detect(header string) bool {
+	return strings.Contains(header, colSampleID) &&
+		strings.Contains(header, colBirthDate) &&
+		strings.Contains(header, flag_label("WBC"))
+}
+
+// DecodeFlag decodes a flag code from the HumaCount export.
+//
+//	L, H    below or above the normal range
+//	LL, HH  below or above the critical(panic) limit
+//	E       measurement error, the value is invalid
+//	*, ?    the value is suspect
+//
+// Unknown codes are treated as suspect.
+func DecodeFlag(code string) cbcparser.Flag {
+	switch strings.TrimSpace(code) {
+	case "":
+		return cbcparser.FlagNone
+	case "L":
+		return cbcparser.FlagLow
+	case "H":
+		return cbcparser.FlagHigh
+	case "LL":
+		return cbcparser.FlagCriticalLow
+	case "HH":
+		return cbcparser.FlagCriticalHigh
+	case "E":
+		return cbcparser.FlagInvalid
+	default:
+		return cbcparser.FlagSuspect
+	}
+}
+
+// Classify returns the kind of run from the Type column.
+// Blank runs are exported with a Sample ID of 0 and a Type of Blank.
+// Sample IDs are otherwise chosen by the lab and never classify a record.
+func Classify(sampleID, typ string) cbcparser.RecordType {
+	switch strings.ToUpper(strings.TrimSpace(typ)) {
+	case "BLANK", "BACKGROUND":
+		return cbcparser.RecordBlank
+	case "CONTROL", "QC":
+		return cbcparser.RecordControl
+	case "CALIBRATION", "CAL":
+		return cbcparser.RecordCalibration
+	case "STARTUP", "START-UP":
+		return cbcparser.RecordStartup
+	}
+
+	if strings.TrimSpace(sampleID) == "0" {
+		return cbcparser.RecordBlank
+	}
+	return cbcparser.RecordPatient
+}
+
 func New() cbcparser.CSVParser {
 	return &HumanCBCResult{}
 }
@@ -70,248 +211,81 @@ func (cbc HumanCBCResult) Write(out io.Writer, format cbcparser.OutFormat) error
 	return err
 }
 
-// Parse reads from r and parses the data into an slice of a CBCWriter struct.
+// Parse reads from r and parses the first record into a result.
 // The text file is expected to be in the tab-separated format.
 // The first line of the file is expected to be the header.
 //
-// The header is expected to be in the following format(with 52 columns):
+// Columns are located by their header, so extra or reordered columns are tolerated.
+// The header is expected to contain the following columns:
 //
 // Sample ID	Date	Time	Patient ID	Birth date	WBC 10^9/l	WBC flag	LYM 10^9/l	LYM flag	MID 10^9/l	MID flag	GRA 10^9/l	GRA flag	LYM% %	LYM% flag	MID% %	MID% flag	GRA% %	GRA% flag	RBC 10^12/l	RBC flag	HGB g/dl	HGB flag	HCT %	HCT flag	MCV fl	MCV flag	MCH pg	MCH flag	MCHC g/dl	MCHC flag	RDWs fl	RDWs flag	RDWc %	RDWc flag	PLT 10^9/l	PLT flag	PCT %	PCT flag	MPV fl	MPV flag	PDWs fl	PDWs flag	PDWc %	PDWc flag	P-LCC 10^9/l	P-LCC flag	P-LCR %	P-LCR flag	Type	Warning
-func (cbc HumanCBCResult) Parse(r io.Reader, normal_ranges *cbcparser.CBCNormalRange) (cbcparser.CBCWriter, error) {
-	reader := csv.NewReader(r)
-	reader.Comma = separator // ',' or '\t'
-	reader.FieldsPerRecord = nfields
-
-	data, err := reader.ReadAll()
-
-	if err != nil {
-		return nil, cbcparser.ErrInvalidCSV
-	}
-
-	if len(data) < 2 {
-		return nil, cbcparser.ErrInsufficientRows
-	}
-
-	// initialize with numrows excluding header row
-	headers := data[0]
-	row := data[1]
-
-	if row[0] == "0" || row[len(row)-2] == "Blank" {
-		return nil, cbcparser.ErrBlankCBCRecord
-	}
-
-	result := set_cbc_value(headers, row, normal_ranges)
-	return result, nil
+func (cbc HumanCBCResult) Parse(r io.Reader, normal_ranges *cbcparser.CBCNormalRange, opts ...cbcparser.Option) (*cbcparser.Result, error) {
+	return NewReader(r, normal_ranges, opts...).rows.First()
 }
 
-// MultiParse reads from r and parses the data into an slice of a CBCWriter struct.
-func (HumanCBCResultMulti) ParseMulti(r io.Reader, normal_ranges *cbcparser.CBCNormalRange) (cbcparser.CBCMultiWriter, error) {
-	reader := csv.NewReader(r)
-	reader.Comma = separator // ',' or '\t'
-	reader.FieldsPerRecord = nfields
-
-	data, err := reader.ReadAll()
-
-	if err != nil {
-		return nil, cbcparser.ErrInvalidCSV
-	}
-
-	if len(data) < 2 {
-		return nil, cbcparser.ErrInsufficientRows
-	}
-
-	headers := data[0]
-	var result []cbcparser.CBCWriter
-
-	for _, row := range data[1:] {
-		if row[0] == "0" || row[len(row)-2] == "Blank" {
-			continue
-		}
-
-		result = append(result, set_cbc_value(headers, row, normal_ranges))
-	}
-
-	return result, nil
+// MultiParse reads from r and parses the data into a slice of results.
+// Use NewReader to process large files without loading them into memory.
+// With WithSkipBadRows, the good records are returned together with cbcparser.RowErrors.
+func (HumanCBCResultMulti) ParseMulti(r io.Reader, normal_ranges *cbcparser.CBCNormalRange, opts ...cbcparser.Option) (cbcparser.Results, error) {
+	return NewReader(r, normal_ranges, opts...).rows.ReadAll()
 }
 
-/*
-row index are as below.
--------------------------
-Indexes: 0 - 4 are identifiers
-Indexes: 5 - 48 have units and flags
-Index: 49 (Type)
-Index: 50 (Warning)
-*/
-func set_cbc_value(headers []string, row []string, normal_ranges *cbcparser.CBCNormalRange) cbcparser.CBCWriter {
+// set_cbc_value builds a record from row using the columns found in the header.
+func (rd *Reader) set_cbc_value(row []string) (HumanCBCResult, error) {
+	cols := rd.rows.Columns
+	normal_ranges := rd.normal_ranges
+
 	cbcRes := HumanCBCResult{}
 	// Patient Identifiers
-	cbcRes.SampleID = row[0]
-	cbcRes.Date = row[1]
-	cbcRes.Time = row[2]
-	cbcRes.PatientID = row[3]
-	cbcRes.BirthDate = row[4]
-
-	// Absoluet counts
-	cbcRes.WBC = cbcparser.CBCValue{
-		Value: parse_float(row[5]),
-		Units: extract_units(headers[5]),
-		Flag:  strings.TrimSpace(row[6]),
-	}
-
-	cbcRes.LYM = cbcparser.CBCValue{
-		Value: parse_float(row[7]),
-		Units: extract_units(headers[7]),
-		Flag:  strings.TrimSpace(row[8]),
-	}
-
-	cbcRes.MID = cbcparser.CBCValue{
-		Value: parse_float(row[9]),
-		Units: extract_units(headers[9]),
-		Flag:  strings.TrimSpace(row[10]),
-	}
-
-	cbcRes.GRA = cbcparser.CBCValue{
-		Value: parse_float(row[11]),
-		Units: extract_units(headers[11]),
-		Flag:  strings.TrimSpace(row[12]),
-	}
-
-	// Percentages
-	cbcRes.LYMPercent = cbcparser.CBCValue{
-		Value: parse_float(row[13]),
-		Units: extract_units(headers[13]),
-		Flag:  strings.TrimSpace(row[14]),
-	}
-
-	cbcRes.MIDPercent = cbcparser.CBCValue{
-		Value: parse_float(row[15]),
-		Units: extract_units(headers[15]),
-		Flag:  strings.TrimSpace(row[16]),
-	}
-
-	cbcRes.GRAPercent = cbcparser.CBCValue{
-		Value: parse_float(row[17]),
-		Units: extract_units(headers[17]),
-		Flag:  strings.TrimSpace(row[18]),
-	}
-
-	// RBCS
-	cbcRes.RBC = cbcparser.CBCValue{
-		Value: parse_float(row[19]),
-		Units: extract_units(headers[19]),
-		Flag:  strings.TrimSpace(row[20]),
-	}
-
-	cbcRes.HGB = cbcparser.CBCValue{
-		Value: parse_float(row[21]),
-		Units: extract_units(headers[21]),
-		Flag:  strings.TrimSpace(row[22]),
-	}
-
-	cbcRes.HCT = cbcparser.CBCValue{
-		Value: parse_float(row[23]),
-		Units: extract_units(headers[23]),
-		Flag:  strings.TrimSpace(row[24]),
-	}
-
-	cbcRes.MCV = cbcparser.CBCValue{
-		Value: parse_float(row[25]),
-		Units: extract_units(headers[25]),
-		Flag:  strings.TrimSpace(row[26]),
-	}
-
-	cbcRes.MCH = cbcparser.CBCValue{
-		Value: parse_float(row[27]),
-		Units: extract_units(headers[27]),
-		Flag:  strings.TrimSpace(row[28]),
-	}
-
-	cbcRes.MCHC = cbcparser.CBCValue{
-		Value: parse_float(row[29]),
-		Units: extract_units(headers[29]),
-		Flag:  strings.TrimSpace(row[30]),
-	}
-
-	cbcRes.RDWs = cbcparser.CBCValue{
-		Value: parse_float(row[31]),
-		Units: extract_units(headers[31]),
-		Flag:  strings.TrimSpace(row[32]),
-	}
-
-	cbcRes.RDWc = cbcparser.CBCValue{
-		Value: parse_float(row[33]),
-		Units: extract_units(headers[33]),
-		Flag:  strings.TrimSpace(row[34]),
-	}
-
-	cbcRes.PLT = cbcparser.CBCValue{
-		Value: parse_float(row[35]),
-		Units: extract_units(headers[35]),
-		Flag:  strings.TrimSpace(row[36]),
-	}
-
-	cbcRes.PCT = cbcparser.CBCValue{
-		Value: parse_float(row[37]),
-		Units: extract_units(headers[37]),
-		Flag:  strings.TrimSpace(row[38]),
-	}
-
-	cbcRes.MPV = cbcparser.CBCValue{
-		Value: parse_float(row[39]),
-		Units: extract_units(headers[39]),
-		Flag:  strings.TrimSpace(row[40]),
-	}
-
-	cbcRes.PDWs = cbcparser.CBCValue{
-		Value: parse_float(row[41]),
-		Units: extract_units(headers[41]),
-		Flag:  strings.TrimSpace(row[42]),
+	cbcRes.SampleID = cols.Get(row, colSampleID)
+	cbcRes.Date = cols.Get(row, colDate)
+	cbcRes.Time = cols.Get(row, colTime)
+	cbcRes.PatientID = cols.Get(row, colPatientID)
+	cbcRes.BirthDate = cols.Get(row, colBirthDate)
+	cbcRes.RecordType = rd.classify(row)
+
+	timestamp, err := rd.rows.Options.ParseDateTime(cbcRes.Date, cbcRes.Time)
+	if err != nil {
+		return cbcRes, rd.rows.FieldError(row, colDate, err)
 	}
+	cbcRes.Timestamp = timestamp
 
-	cbcRes.PDWc = cbcparser.CBCValue{
-		Value: parse_float(row[43]),
-		Units: extract_units(headers[43]),
-		Flag:  strings.TrimSpace(row[44]),
-	}
+	birth, err := rd.rows.Options.ParseDate(cbcRes.BirthDate)
+	if err != nil {
+		return cbcRes, rd.rows.FieldError(row, colBirthDate, err)
+	}
+	cbcRes.DateOfBirth = birth
+
+	values := cbcRes.values()
+	for _, a := range analytes {
+		cell := cols.Get(row, a.label)
+		value, status := rd.rows.Options.ParseValue(cell)
+		flag := strings.TrimSpace(cols.Get(row, flag_label(a.label)))
+
+		unit, _ := units.Parse(cols.Units(a.label))
+
+		v := values[a.param]
+		*v = cbcparser.CBCValue{
+			Value:   value,
+			Status:  status,
+			Units:   cols.Units(a.label),
+			UCUM:    unit.UCUM,
+			LOINC:   a.param.LOINC(),
+			Flag:    DecodeFlag(flag),
+			RawFlag: flag,
+		}
 
-	cbcRes.PLCC = cbcparser.CBCValue{
-		Value: parse_float(row[45]),
-		Units: extract_units(headers[45]),
-		Flag:  strings.TrimSpace(row[46]),
-	}
-	cbcRes.PLCR = cbcparser.CBCValue{
-		Value: parse_float(row[47]),
-		Units: extract_units(headers[47]),
-		Flag:  strings.TrimSpace(row[48]),
-	}
+		if status != cbcparser.StatusOK {
+			v.Raw = cell
+		}
 
-	cbcRes.Type = row[49]
-	cbcRes.Warning = row[50]
-
-	if normal_ranges != nil {
-		cbcRes.WBC.NormalRange = normal_ranges.WBC
-		cbcRes.LYM.NormalRange = normal_ranges.LYM
-		cbcRes.LYMPercent.NormalRange = normal_ranges.LYMPercent
-		cbcRes.MID.NormalRange = normal_ranges.MID
-		cbcRes.MIDPercent.NormalRange = normal_ranges.MIDPercent
-		cbcRes.GRA.NormalRange = normal_ranges.GRA
-		cbcRes.GRAPercent.NormalRange = normal_ranges.GRAPercent
-		cbcRes.RBC.NormalRange = normal_ranges.RBC
-		cbcRes.HGB.NormalRange = normal_ranges.HGB
-		cbcRes.HCT.NormalRange = normal_ranges.HCT
-		cbcRes.MCV.NormalRange = normal_ranges.MCV
-		cbcRes.MCH.NormalRange = normal_ranges.MCH
-		cbcRes.MCHC.NormalRange = normal_ranges.MCHC
-		cbcRes.RDWc.NormalRange = normal_ranges.RDWc
-		cbcRes.RDWs.NormalRange = normal_ranges.RDWs
-		cbcRes.PLT.NormalRange = normal_ranges.PLT
-		cbcRes.PDWs.NormalRange = normal_ranges.PDWs
-		cbcRes.PDWc.NormalRange = normal_ranges.PDWc
-		cbcRes.MPV.NormalRange = normal_ranges.MPV
-		cbcRes.PCT.NormalRange = normal_ranges.PCT
-		cbcRes.PLCC.NormalRange = normal_ranges.PLCC
-		cbcRes.PLCR.NormalRange = normal_ranges.PLCR
+		if normal_ranges != nil {
+			v.NormalRange = normal_ranges.Range(a.param)
+		}
 	}
 
-	return cbcRes
+	cbcRes.Type = cols.Get(row, colType)
+	cbcRes.Warning = cols.Get(row, colWarning)
+	cbcRes.Alarms = DecodeWarning(cbcRes.Warning)
+	return cbcRes, nil
 }
@@ -1,6 +1,11 @@
 package human
 
-import "github.com/abiiranathan/cbcparser/cbcparser"
+import (
+	"strings"
+	"time"
+
+	"github.com/abiiranathan/cbcparser/cbcparser"
+)
 
 // Structure to store data parsed from the text file
 // exported by the HUMAN 30 CBC Machine.
@@ -11,6 +16,14 @@ type HumanCBCResult struct {
 	PatientID string `json:"patient_id"`
 	BirthDate string `json:"birth_date"`
 
+	// Kind of run, derived from the Sample ID and Type columns.
+	RecordType cbcparser.RecordType `json:"record_type"`
+
+	// Date and Time parsed in the lab's time zone.
+	Timestamp *time.Time `json:"timestamp"`
+	// BirthDate parsed, nil for placeholders such as 00/00/0000.
+	DateOfBirth *time.Time `json:"date_of_birth"`
+
 	WBC        cbcparser.CBCValue `json:"wbc"`
 	LYM        cbcparser.CBCValue `json:"lym"`
 	MID        cbcparser.CBCValue `json:"mid"`
@@ -36,6 +49,66 @@ type HumanCBCResult struct {
 
 	Type    string `json:"type"`
 	Warning string `json:"warning"`
+
+	// Warning decoded into alarms.
+	Alarms []cbcparser.Alarm `json:"alarms"`
 }
 
 type HumanCBCResultMulti []HumanCBCResult
+
+// Instrument identifies the HumaCount 30TS.
+var Instrument = cbcparser.Instrument{
+	Manufacturer: "Human Diagnostics",
+	Model:        "HumaCount 30TS",
+}
+
+// values returns pointers to the analytes of cbc keyed by parameter.
+func (cbc *HumanCBCResult) values() map[cbcparser.Parameter]*cbcparser.CBCValue {
+	return map[cbcparser.Parameter]*cbcparser.CBCValue{
+		cbcparser.WBC:        &cbc.WBC,
+		cbcparser.LYM:        &cbc.LYM,
+		cbcparser.MID:        &cbc.MID,
+		cbcparser.GRA:        &cbc.GRA,
+		cbcparser.LYMPercent: &cbc.LYMPercent,
+		cbcparser.MIDPercent: &cbc.MIDPercent,
+		cbcparser.GRAPercent: &cbc.GRAPercent,
+		cbcparser.RBC:        &cbc.RBC,
+		cbcparser.HGB:        &cbc.HGB,
+		cbcparser.HCT:        &cbc.HCT,
+		cbcparser.MCV:        &cbc.MCV,
+		cbcparser.MCH:        &cbc.MCH,
+		cbcparser.MCHC:       &cbc.MCHC,
+		cbcparser.RDWs:       &cbc.RDWs,
+		cbcparser.RDWc:       &cbc.RDWc,
+		cbcparser.PLT:        &cbc.PLT,
+		cbcparser.PCT:        &cbc.PCT,
+		cbcparser.MPV:        &cbc.MPV,
+		cbcparser.PDWs:       &cbc.PDWs,
+		cbcparser.PDWc:       &cbc.PDWc,
+		cbcparser.PLCC:       &cbc.PLCC,
+		cbcparser.PLCR:       &cbc.PLCR,
+	}
+}
+
+// Result converts cbc into the machine-neutral result.
+// cbc is kept as the raw view of the result.
+func (cbc HumanCBCResult) Result() *cbcparser.Result {
+	res := cbcparser.NewResult(Instrument)
+	res.SampleID = cbc.SampleID
+	res.PatientID = cbc.PatientID
+	res.Timestamp = cbc.Timestamp
+	res.BirthDate = cbc.DateOfBirth
+	res.Alarms = append(res.Alarms, cbc.Alarms...)
+	res.RecordType = cbc.RecordType
+	res.Raw = cbc
+
+	// The type of blank, control and calibration runs is not a species.
+	if cbc.RecordType == cbcparser.RecordPatient {
+		res.Species = strings.TrimSpace(cbc.Type)
+	}
+
+	for p, v := range cbc.values() {
+		res.Set(p, *v)
+	}
+	return res
+}
@@ -0,0 +1,53 @@
+package human
+
+import (
+	"io"
+
+	"github.com/abiiranathan/cbcparser/cbcparser"
+)
+
+// Reader reads HumaCount records one row at a time.
+// Records of types not selected with cbcparser.WithRecordTypes are skipped,
+// by default only patient records are returned.
+type Reader struct {
+	rows          *cbcparser.RowReader
+	normal_ranges *cbcparser.CBCNormalRange
+}
+
+// NewReader returns a streaming reader for the tab-separated export in r.
+func NewReader(r io.Reader, normal_ranges *cbcparser.CBCNormalRange, opts ...cbcparser.Option) *Reader {
+	rd := &Reader{
+		rows:          cbcparser.NewRowReader(r, separator, split_header, required_columns(), cbcparser.NewOptions(opts...)),
+		normal_ranges: normal_ranges,
+	}
+
+	rd.rows.Validate = validate_units
+	rd.rows.Classify = rd.classify
+	rd.rows.Build = rd.build
+	return rd
+}
+
+// Next returns the next patient record or io.EOF at the end of the file.
+// With WithSkipBadRows, rows that cannot be parsed are skipped and
+// reported by Errors.
+func (rd *Reader) Next() (*cbcparser.Result, error) {
+	return rd.rows.Next()
+}
+
+// Errors returns the errors of the rows skipped so far.
+func (rd *Reader) Errors() cbcparser.RowErrors {
+	return rd.rows.Errors()
+}
+
+func (rd *Reader) classify(row []string) cbcparser.RecordType {
+	cols := rd.rows.Columns
+	return Classify(cols.Get(row, colSampleID), cols.Get(row, colType))
+}
+
+func (rd *Reader) build(row []string) (*cbcparser.Result, error) {
+	cbc, err := rd.set_cbc_value(row)
+	if err != nil {
+		return nil, err
+	}
+	return cbc.Result(), nil
+}
@@ -0,0 +1,89 @@
+package human
+
+import (
+	"strings"
+
+	"github.com/abiiranathan/cbcparser/cbcparser"
+)
+
+var (
+	wbcDiff = []cbcparser.Parameter{
+		cbcparser.LYM, cbcparser.MID, cbcparser.GRA,
+		cbcparser.LYMPercent, cbcparser.MIDPercent, cbcparser.GRAPercent,
+	}
+
+	pltIndices = []cbcparser.Parameter{
+		cbcparser.PLT, cbcparser.PCT, cbcparser.MPV, cbcparser.PDWs,
+		cbcparser.PDWc, cbcparser.PLCC, cbcparser.PLCR,
+	}
+)
+
+// Warning letters written by the HumaCount 30TS in the Warning column.
+// Letters are case-sensitive.
+var warnings = map[rune]cbcparser.Alarm{
+	'l': {
+		Description: "Low WBC count",
+		Parameters:  append([]cbcparser.Parameter{cbcparser.WBC}, wbcDiff...),
+		Severity:    cbcparser.SeverityWarning,
+	},
+	'h': {
+		Description: "High WBC count",
+		Parameters:  append([]cbcparser.Parameter{cbcparser.WBC}, wbcDiff...),
+		Severity:    cbcparser.SeverityWarning,
+	},
+	'L': {
+		Description: "Error in LYM",
+		Parameters:  []cbcparser.Parameter{cbcparser.LYM, cbcparser.LYMPercent},
+		Severity:    cbcparser.SeverityError,
+	},
+	'M': {
+		Description: "Error in MID",
+		Parameters:  []cbcparser.Parameter{cbcparser.MID, cbcparser.MIDPercent},
+		Severity:    cbcparser.SeverityError,
+	},
+	'G': {
+		Description: "Error in GRA",
+		Parameters:  []cbcparser.Parameter{cbcparser.GRA, cbcparser.GRAPercent},
+		Severity:    cbcparser.SeverityError,
+	},
+	'E': {
+		Description: "Error in LYM-MID-GRA",
+		Parameters:  wbcDiff,
+		Severity:    cbcparser.SeverityError,
+	},
+	'W': {
+		Description: "Error in WBC histogram",
+		Parameters:  append([]cbcparser.Parameter{cbcparser.WBC}, wbcDiff...),
+		Severity:    cbcparser.SeverityError,
+	},
+	'R': {
+		Description: "Error in RBC-PLT discrimination",
+		Parameters:  append([]cbcparser.Parameter{cbcparser.RBC}, pltIndices...),
+		Severity:    cbcparser.SeverityError,
+	},
+	'P': {
+		Description: "Platelet clumps or giant platelets suspected",
+		Parameters:  pltIndices,
+		Severity:    cbcparser.SeverityWarning,
+	},
+}
+
+// DecodeWarning expands the letters of the Warning column(e.g "lLE") into alarms.
+// Unknown letters are reported as alarms with a generic description.
+func DecodeWarning(warning string) []cbcparser.Alarm {
+	var alarms []cbcparser.Alarm
+	for _, letter := range strings.TrimSpace(warning) {
+		alarm, ok := warnings[letter]
+		if !ok {
+			alarm = cbcparser.Alarm{
+				Description: "Unknown warning " + string(letter),
+				Severity:    cbcparser.SeverityWarning,
+			}
+		}
+
+		alarm.Code = string(letter)
+		alarm.Source = cbcparser.SourceInstrument
+		alarms = append(alarms, alarm)
+	}
+	return alarms
+}
@@ -0,0 +1,175 @@
+// Package indices computes derived hematology indices such as the
+// Mentzer index from the values of a parsed result.
+//
+//	indices.ApplyAll(results)
+//
+// Labs can add their own formulas with Register.
+package indices
+
+import (
+	"math"
+	"sync"
+
+	"github.com/abiiranathan/cbcparser/cbcparser"
+	"github.com/abiiranathan/cbcparser/cbcparser/units"
+)
+
+// Names of the built-in indices.
+const (
+	Mentzer   = "mentzer"
+	NLR       = "nlr"
+	PLR       = "plr"
+	RDWIndex  = "rdw_index"
+	GreenKing = "green_king"
+)
+
+// Formula computes an index from the values of a result.
+// Compute returns false when an input is missing or the index is undefined.
+type Formula struct {
+	Name        string
+	Description string
+	Units       string
+	Compute     func(res *cbcparser.Result) (float64, bool)
+}
+
+var (
+	formulasMu sync.RWMutex
+	formulas   []Formula
+)
+
+func init() {
+	Register(Formula{
+		Name:        Mentzer,
+		Description: "Mentzer index(MCV/RBC)",
+		Compute: func(res *cbcparser.Result) (float64, bool) {
+			return ratio(res, cbcparser.MCV, "fL", cbcparser.RBC, "10^12/L")
+		},
+	})
+
+	Register(Formula{
+		Name:        NLR,
+		Description: "Neutrophil-to-lymphocyte ratio(GRA/LYM)",
+		Compute: func(res *cbcparser.Result) (float64, bool) {
+			return ratio(res, cbcparser.GRA, "10^9/L", cbcparser.LYM, "10^9/L")
+		},
+	})
+
+	Register(Formula{
+		Name:        PLR,
+		Description: "Platelet-to-lymphocyte ratio(PLT/LYM)",
+		Compute: func(res *cbcparser.Result) (float64, bool) {
+			return ratio(res, cbcparser.PLT, "10^9/L", cbcparser.LYM, "10^9/L")
+		},
+	})
+
+	Register(Formula{
+		Name:        RDWIndex,
+		Description: "RDW index(MCV×RDW-CV/RBC)",
+		Compute: func(res *cbcparser.Result) (float64, bool) {
+			mcv, ok1 := Value(res, cbcparser.MCV, "fL")
+			rdw, ok2 := Value(res, cbcparser.RDWc, "%")
+			rbc, ok3 := Value(res, cbcparser.RBC, "10^12/L")
+			if !ok1 || !ok2 || !ok3 || rbc == 0 {
+				return 0, false
+			}
+			return mcv * rdw / rbc, true
+		},
+	})
+
+	Register(Formula{
+		Name:        GreenKing,
+		Description: "Green & King index(MCV²×RDW-CV/(HGB×100))",
+		Compute: func(res *cbcparser.Result) (float64, bool) {
+			mcv, ok1 := Value(res, cbcparser.MCV, "fL")
+			rdw, ok2 := Value(res, cbcparser.RDWc, "%")
+			hgb, ok3 := Value(res, cbcparser.HGB, "g/dL")
+			if !ok1 || !ok2 || !ok3 || hgb == 0 {
+				return 0, false
+			}
+			return mcv * mcv * rdw / (hgb * 100), true
+		},
+	})
+}
+
+// Register adds a formula computed by Apply after the built-in indices.
+// Register panics if called twice with the same name or with a nil Compute.
+func Register(f Formula) {
+	formulasMu.Lock()
+	defer formulasMu.Unlock()
+
+	if f.Compute == nil {
+		panic("indices: Register Compute is nil")
+	}
+
+	for _, g := range formulas {
+		if g.Name == f.Name {
+			panic("indices: Register called twice for " + f.Name)
+		}
+	}
+	formulas = append(formulas, f)
+}
+
+// Formulas returns the registered formulas in order of registration.
+func Formulas() []Formula {
+	formulasMu.RLock()
+	defer formulasMu.RUnlock()
+	return append([]Formula(nil), formulas...)
+}
+
+// Compute returns the indices that can be computed from res.
+func Compute(res *cbcparser.Result) []cbcparser.CalculatedValue {
+	var values []cbcparser.CalculatedValue
+	for _, f := range Formulas() {
+		v, ok := f.Compute(res)
+		if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+
+		values = append(values, cbcparser.CalculatedValue{
+			Name:        f.Name,
+			Description: f.Description,
+			Value:       float32(v),
+			Units:       f.Units,
+			Provenance:  cbcparser.ProvenanceCalculated,
+		})
+	}
+	return values
+}
+
+// Apply attaches the indices that can be computed from res to its calculated values.
+func Apply(res *cbcparser.Result) {
+	for _, v := range Compute(res) {
+		res.AddCalculated(v)
+	}
+}
+
+// ApplyAll calls Apply for every result.
+func ApplyAll(results cbcparser.Results) {
+	for _, res := range results {
+		Apply(res)
+	}
+}
+
+// Value returns the value of p in res converted to unit. It returns false
+// if p was not measured or its units cannot be converted to unit.
+func Value(res *cbcparser.Result, p cbcparser.Parameter, unit string) (float64, bool) {
+	v, ok := res.Value(p)
+	if !ok || !v.Valid() {
+		return 0, false
+	}
+
+	v, err := units.Convert(p, v, unit)
+	if err != nil {
+		return 0, false
+	}
+	return float64(v.Value), true
+}
+
+func ratio(res *cbcparser.Result, num cbcparser.Parameter, numUnit string, den cbcparser.Parameter, denUnit string) (float64, bool) {
+	n, ok1 := Value(res, num, numUnit)
+	d, ok2 := Value(res, den, denUnit)
+	if !ok1 || !ok2 || d == 0 {
+		return 0, false
+	}
+	return n / d, true
+}
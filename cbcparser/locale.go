@@ -0,0 +1,90 @@
+package cbcparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	decimalComma = regexp.MustCompile(`^-?\d+,\d+$`)
+	decimalPoint = regexp.MustCompile(`^-?\d+\.\d+$`)
+)
+
+// Field separators recognised when detecting the separator from the header.
+var fieldSeparators = []rune{'\t', ';', ','}
+
+// DetectSeparators returns the field and decimal separators used by an export
+// from its header and first data line. fallback is returned as the field
+// separator when the header contains none of tab, semicolon or comma.
+//
+// The decimal separator is a comma when the data line contains more numbers
+// with a decimal comma than with a decimal point. Without numbers, exports
+// separated by semicolons are assumed to use a decimal comma.
+func DetectSeparators(header, line string, fallback rune) (field, decimal rune) {
+	field = fallback
+	max := 0
+	for _, sep := range fieldSeparators {
+		if n := strings.Count(header, string(sep)); n > max {
+			field, max = sep, n
+		}
+	}
+
+	if field == ',' {
+		return field, '.'
+	}
+
+	var commas, points int
+	for _, cell := range strings.Split(line, string(field)) {
+		cell = strings.TrimSpace(cell)
+		if decimalComma.MatchString(cell) {
+			commas++
+		} else if decimalPoint.MatchString(cell) {
+			points++
+		}
+	}
+
+	switch {
+	case commas > points:
+		return field, ','
+	case points > commas:
+		return field, '.'
+	case field == ';':
+		return field, ','
+	}
+	return field, '.'
+}
+
+// ParseValue parses a numeric cell using the decimal and thousands separators
+// of the options. See the ParseValue function.
+func (o *Options) ParseValue(cell string) (float32, ValueStatus) {
+	if o.ThousandsSeparator != 0 {
+		cell = strings.ReplaceAll(cell, string(o.ThousandsSeparator), "")
+	}
+
+	if o.DecimalSeparator != 0 && o.DecimalSeparator != '.' {
+		cell = strings.ReplaceAll(cell, string(o.DecimalSeparator), ".")
+	}
+	return ParseValue(cell)
+}
+
+// WithFieldSeparator sets the field separator instead of detecting it from the header.
+func WithFieldSeparator(sep rune) Option {
+	return func(o *Options) {
+		o.FieldSeparator = sep
+	}
+}
+
+// WithDecimalSeparator sets the decimal separator instead of detecting it.
+func WithDecimalSeparator(sep rune) Option {
+	return func(o *Options) {
+		o.DecimalSeparator = sep
+	}
+}
+
+// WithThousandsSeparator sets the separator used to group thousands e.g '.' in 1.234,5.
+// Numbers are not grouped by default.
+func WithThousandsSeparator(sep rune) Option {
+	return func(o *Options) {
+		o.ThousandsSeparator = sep
+	}
+}
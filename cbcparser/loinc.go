@@ -0,0 +1,73 @@
+package cbcparser
+
+// LOINCSystem is the system URI of LOINC codes as used by FHIR and HL7.
+const LOINCSystem = "http://loinc.org"
+
+// Code is a concept of a standard terminology e.g a LOINC test code.
+type Code struct {
+	System  string `json:"system"`
+	Code    string `json:"code"`
+	Display string `json:"display"`
+}
+
+// LOINC codes of the parameters, all measured in blood by automated count.
+// MID(mid-sized cells: monocytes, eosinophils and basophils) and P-LCC have
+// no exact LOINC equivalent and no code by default, see MissingLOINC.
+var loinc = map[Parameter]Code{
+	WBC:        {LOINCSystem, "6690-2", "Leukocytes [#/volume] in Blood by Automated count"},
+	LYM:        {LOINCSystem, "731-0", "Lymphocytes [#/volume] in Blood by Automated count"},
+	GRA:        {LOINCSystem, "20482-6", "Granulocytes [#/volume] in Blood by Automated count"},
+	LYMPercent: {LOINCSystem, "736-9", "Lymphocytes/100 leukocytes in Blood by Automated count"},
+	GRAPercent: {LOINCSystem, "19023-1", "Granulocytes/100 leukocytes in Blood by Automated count"},
+	RBC:        {LOINCSystem, "789-8", "Erythrocytes [#/volume] in Blood by Automated count"},
+	HGB:        {LOINCSystem, "718-7", "Hemoglobin [Mass/volume] in Blood"},
+	HCT:        {LOINCSystem, "4544-3", "Hematocrit [Volume Fraction] of Blood by Automated count"},
+	MCV:        {LOINCSystem, "787-2", "MCV [Entitic volume] by Automated count"},
+	MCH:        {LOINCSystem, "785-6", "MCH [Entitic mass] by Automated count"},
+	MCHC:       {LOINCSystem, "786-4", "MCHC [Mass/volume] by Automated count"},
+	RDWs:       {LOINCSystem, "21000-5", "Erythrocyte distribution width [Entitic volume] by Automated count"},
+	RDWc:       {LOINCSystem, "788-0", "Erythrocyte distribution width [Ratio] by Automated count"},
+	PLT:        {LOINCSystem, "777-3", "Platelets [#/volume] in Blood by Automated count"},
+	PCT:        {LOINCSystem, "51637-7", "Plateletcrit [Volume Fraction] in Blood by Automated count"},
+	MPV:        {LOINCSystem, "32623-1", "Platelet mean volume [Entitic volume] in Blood by Automated count"},
+	PDW:        {LOINCSystem, "32207-3", "Platelet distribution width [Entitic volume] in Blood by Automated count"},
+	PDWs:       {LOINCSystem, "32207-3", "Platelet distribution width [Entitic volume] in Blood by Automated count"},
+	PDWc:       {LOINCSystem, "51631-0", "Platelet distribution width [Ratio] in Blood by Automated count"},
+	PLCR:       {LOINCSystem, "48386-7", "Platelets Large/Platelets in Blood by Automated count"},
+}
+
+// LOINC returns the LOINC code of p or nil if p has no code.
+func (p Parameter) LOINC() *Code {
+	c, ok := loinc[p]
+	if !ok {
+		return nil
+	}
+	return &c
+}
+
+// MissingLOINC returns the parameters without a LOINC code in report order.
+// Their values are exported without a code unless one is set with SetLOINC.
+func MissingLOINC() []Parameter {
+	var params []Parameter
+	for _, p := range Parameters() {
+		if _, ok := loinc[p]; !ok {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+// SetLOINC overrides the LOINC code of p e.g to map MID to a code used by the lab.
+// A zero code removes the mapping. SetLOINC is not safe for concurrent use with
+// parsing and should be called during initialization.
+func SetLOINC(p Parameter, code Code) {
+	if code == (Code{}) {
+		delete(loinc, p)
+		return
+	}
+
+	if code.System == "" {
+		code.System = LOINCSystem
+	}
+	loinc[p] = code
+}
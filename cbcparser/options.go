@@ -0,0 +1,117 @@
+package cbcparser
+
+import "time"
+
+// Options control how records are parsed.
+type Options struct {
+	// Name of the file being parsed, reported in parse errors.
+	FileName string
+
+	// Skip rows that cannot be parsed instead of failing the whole file.
+	// The skipped rows are reported as RowErrors.
+	SkipBadRows bool
+
+	// Order of day and month in exported dates. Defaults to DayFirst.
+	DateOrder DateOrder
+	// Layout of exported dates(see time.Parse). Overrides DateOrder.
+	DateLayout string
+	// Layout of exported times of day. Defaults to "15:04" or "15:04:05".
+	TimeLayout string
+	// Time zone of the lab. Defaults to time.Local.
+	Location *time.Location
+
+	// Types of records returned. Defaults to patient records only.
+	RecordTypes []RecordType
+
+	// Character encoding of the export. Detected when empty.
+	Encoding Encoding
+
+	// Separators of fields and numbers. Detected from the export when 0.
+	FieldSeparator     rune
+	DecimalSeparator   rune
+	ThousandsSeparator rune
+
+	// Tolerances of the consistency checks between red cell indices.
+	// Defaults to DefaultTolerances, nil disables the checks.
+	Tolerances *Tolerances
+
+	// Limits outside which values are rejected as corrupted.
+	// Defaults to DefaultPlausibilityLimits, nil disables the check.
+	Plausibility Limits
+	// Analytical measuring ranges keyed by instrument.
+	MeasuringRanges map[Instrument]Limits
+
+	// Completes the demographics of results from e.g the LIS.
+	Patients PatientLookup
+	// Ranges partitioned by sex and age. Replace the normal ranges passed to the parser.
+	ReferenceRanges *ReferenceRanges
+	// Selects the ranges of each result e.g by instrument. Takes precedence
+	// over ReferenceRanges for the results it has ranges for.
+	RangeSelector RangeSelector
+
+	// Serial number of the instrument, which the exports do not contain.
+	InstrumentSerial string
+
+	// Notified of patient results with critical values once the file is read.
+	Alerters []Alerter
+}
+
+// Option configures Options.
+type Option func(o *Options)
+
+// NewOptions returns the options obtained by applying opts to the defaults.
+func NewOptions(opts ...Option) *Options {
+	o := &Options{
+		Tolerances:   DefaultTolerances(),
+		Plausibility: DefaultPlausibilityLimits(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithFileName sets the file name reported in parse errors.
+func WithFileName(name string) Option {
+	return func(o *Options) {
+		o.FileName = name
+	}
+}
+
+// WithSkipBadRows makes ParseMulti return the good records together with
+// RowErrors for the rows that could not be parsed.
+func WithSkipBadRows() Option {
+	return func(o *Options) {
+		o.SkipBadRows = true
+	}
+}
+
+// WithDateOrder sets the order of day and month in exported dates.
+func WithDateOrder(order DateOrder) Option {
+	return func(o *Options) {
+		o.DateOrder = order
+	}
+}
+
+// WithDateLayout sets the layouts(see time.Parse) of exported dates and times of day.
+// An empty layout keeps the default.
+func WithDateLayout(date, clock string) Option {
+	return func(o *Options) {
+		o.DateLayout = date
+		o.TimeLayout = clock
+	}
+}
+
+// WithInstrumentSerial sets the serial number of the instrument that produced the export.
+func WithInstrumentSerial(serial string) Option {
+	return func(o *Options) {
+		o.InstrumentSerial = serial
+	}
+}
+
+// WithLocation sets the time zone of the lab.
+func WithLocation(loc *time.Location) Option {
+	return func(o *Options) {
+		o.Location = loc
+	}
+}
@@ -0,0 +1,118 @@
+package cbcparser
+
+import "fmt"
+
+// Parameter identifies a single CBC analyte independently of the
+// machine that measured it.
+type Parameter int
+
+const (
+	WBC Parameter = iota
+	LYM
+	MID
+	GRA
+	LYMPercent
+	MIDPercent
+	GRAPercent
+	RBC
+	HGB
+	HCT
+	MCV
+	MCH
+	MCHC
+	RDWs
+	RDWc
+	PLT
+	PCT
+	MPV
+	PDW
+	PDWs
+	PDWc
+	PLCC
+	PLCR
+)
+
+// JSON keys match the keys used in the normal ranges file.
+var parameterInfo = [...]struct {
+	key  string
+	name string
+}{
+	WBC:        {"wbc", "WBC"},
+	LYM:        {"lym", "LYM#"},
+	MID:        {"mid", "MID#"},
+	GRA:        {"gra", "GRA#"},
+	LYMPercent: {"lym_percent", "LYM%"},
+	MIDPercent: {"mid_percent", "MID%"},
+	GRAPercent: {"gra_percent", "GRA%"},
+	RBC:        {"rbc", "RBC"},
+	HGB:        {"hgb", "HGB"},
+	HCT:        {"hct", "HCT"},
+	MCV:        {"mcv", "MCV"},
+	MCH:        {"mch", "MCH"},
+	MCHC:       {"mchc", "MCHC"},
+	RDWs:       {"rdw_s", "RDW-SD"},
+	RDWc:       {"rdw_c", "RDW-CV"},
+	PLT:        {"plt", "PLT"},
+	PCT:        {"pct", "PCT"},
+	MPV:        {"mpv", "MPV"},
+	PDW:        {"pdw", "PDW"},
+	PDWs:       {"pdw_s", "PDW-SD"},
+	PDWc:       {"pdw_c", "PDW-CV"},
+	PLCC:       {"plcc", "P-LCC"},
+	PLCR:       {"plcr", "P-LCR"},
+}
+
+// Parameters returns all known parameters in report order.
+func Parameters() []Parameter {
+	params := make([]Parameter, len(parameterInfo))
+	for i := range parameterInfo {
+		params[i] = Parameter(i)
+	}
+	return params
+}
+
+// ParseParameter returns the parameter with the given JSON key (e.g "lym_percent").
+func ParseParameter(key string) (Parameter, error) {
+	for i, info := range parameterInfo {
+		if info.key == key {
+			return Parameter(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown cbc parameter %q", key)
+}
+
+func (p Parameter) valid() bool {
+	return p >= 0 && int(p) < len(parameterInfo)
+}
+
+// Key returns the JSON key of the parameter e.g "lym_percent".
+func (p Parameter) Key() string {
+	if !p.valid() {
+		return fmt.Sprintf("parameter(%d)", int(p))
+	}
+	return parameterInfo[p].key
+}
+
+// String returns the display name of the parameter e.g "LYM%".
+func (p Parameter) String() string {
+	if !p.valid() {
+		return fmt.Sprintf("Parameter(%d)", int(p))
+	}
+	return parameterInfo[p].name
+}
+
+func (p Parameter) MarshalText() ([]byte, error) {
+	if !p.valid() {
+		return nil, fmt.Errorf("invalid cbc parameter %d", int(p))
+	}
+	return []byte(p.Key()), nil
+}
+
+func (p *Parameter) UnmarshalText(text []byte) error {
+	v, err := ParseParameter(string(text))
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
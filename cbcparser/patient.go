@@ -0,0 +1,47 @@
+package cbcparser
+
+import "time"
+
+// Sex of a patient, used to select reference ranges.
+type Sex string
+
+const (
+	SexUnknown Sex = ""
+	Male       Sex = "male"
+	Female     Sex = "female"
+)
+
+// Patient holds the demographics of a patient that the machine
+// does not export, e.g from the LIS.
+type Patient struct {
+	ID        string
+	Sex       Sex
+	BirthDate *time.Time
+}
+
+// PatientLookup returns the patient a result belongs to or nil if unknown.
+type PatientLookup func(res *Result) *Patient
+
+// WithPatients sets the lookup used to complete the patient ID, sex and date
+// of birth of results e.g for Edan exports, which carry none of them.
+// Fields exported by the machine are kept.
+func WithPatients(lookup PatientLookup) Option {
+	return func(o *Options) {
+		o.Patients = lookup
+	}
+}
+
+// complete fills the demographics of res missing from the export.
+func (p *Patient) complete(res *Result) {
+	if res.PatientID == "" {
+		res.PatientID = p.ID
+	}
+
+	if res.Sex == SexUnknown {
+		res.Sex = p.Sex
+	}
+
+	if res.BirthDate == nil {
+		res.BirthDate = p.BirthDate
+	}
+}
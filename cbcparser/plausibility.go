@@ -0,0 +1,145 @@
+package cbcparser
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Limit is the lowest and highest acceptable value of a parameter.
+type Limit struct {
+	Lower float32 `json:"lower"`
+	Upper float32 `json:"upper"`
+
+	// UCUM codes of the units of Lower and Upper e.g "10*9/L" and the equal
+	// "10*3/uL". Values in other units are not checked. Empty applies
+	// the limit to values in any units.
+	Units []string `json:"units,omitempty"`
+}
+
+// applies reports whether lim is in the units of v.
+func (lim Limit) applies(v CBCValue) bool {
+	if len(lim.Units) == 0 {
+		return true
+	}
+
+	for _, u := range lim.Units {
+		if v.UCUM == u {
+			return true
+		}
+	}
+	return false
+}
+
+// Limits are the lowest and highest acceptable values of each parameter.
+// Parameters without limits are not checked.
+type Limits map[Parameter]Limit
+
+// DefaultPlausibilityLimits returns absolute limits in conventional units
+// (10^9/L, 10^12/L, g/dL, %, fL, pg) outside which a value cannot be
+// physiological and the export is assumed to be corrupted. Values in
+// other units e.g HGB in g/L are not checked.
+func DefaultPlausibilityLimits() Limits {
+	var (
+		count   = []string{"10*9/L", "10*3/uL"}
+		rbc     = []string{"10*12/L", "10*6/uL"}
+		mass    = []string{"g/dL"}
+		percent = []string{"%"}
+		fl      = []string{"fL"}
+		pg      = []string{"pg"}
+	)
+
+	return Limits{
+		WBC:        {Lower: 0, Upper: 500, Units: count},
+		LYM:        {Lower: 0, Upper: 500, Units: count},
+		MID:        {Lower: 0, Upper: 500, Units: count},
+		GRA:        {Lower: 0, Upper: 500, Units: count},
+		LYMPercent: {Lower: 0, Upper: 100, Units: percent},
+		MIDPercent: {Lower: 0, Upper: 100, Units: percent},
+		GRAPercent: {Lower: 0, Upper: 100, Units: percent},
+		RBC:        {Lower: 0, Upper: 15, Units: rbc},
+		HGB:        {Lower: 0, Upper: 30, Units: mass},
+		HCT:        {Lower: 0, Upper: 100, Units: percent},
+		MCV:        {Lower: 0, Upper: 200, Units: fl},
+		MCH:        {Lower: 0, Upper: 100, Units: pg},
+		MCHC:       {Lower: 0, Upper: 100, Units: mass},
+		RDWs:       {Lower: 0, Upper: 200, Units: fl},
+		RDWc:       {Lower: 0, Upper: 100, Units: percent},
+		PLT:        {Lower: 0, Upper: 5000, Units: count},
+		PCT:        {Lower: 0, Upper: 100, Units: percent},
+		MPV:        {Lower: 0, Upper: 50, Units: fl},
+		PDW:        {Lower: 0, Upper: 100},
+		PDWs:       {Lower: 0, Upper: 100, Units: fl},
+		PDWc:       {Lower: 0, Upper: 100, Units: percent},
+		PLCC:       {Lower: 0, Upper: 5000, Units: count},
+		PLCR:       {Lower: 0, Upper: 100, Units: percent},
+	}
+}
+
+// WithPlausibilityLimits sets the limits outside which values are rejected.
+// A nil limits disables the check.
+func WithPlausibilityLimits(limits Limits) Option {
+	return func(o *Options) {
+		o.Plausibility = limits
+	}
+}
+
+// WithMeasuringRange sets the analytical measuring range(linearity limits) of
+// an instrument as given in its manual. Values outside it are reported as
+// ">upper" or "<lower".
+func WithMeasuringRange(instrument Instrument, limits Limits) Option {
+	return func(o *Options) {
+		if o.MeasuringRanges == nil {
+			o.MeasuringRanges = make(map[Instrument]Limits)
+		}
+		o.MeasuringRanges[instrument] = limits
+	}
+}
+
+// CheckPlausibility rejects the values of res outside limits in their units.
+// Rejected values are no longer valid, keep the exported value in Raw and
+// are flagged invalid.
+func CheckPlausibility(res *Result, limits Limits) {
+	for p, v := range res.Values {
+		lim, ok := limits[p]
+		if !ok || !v.Valid() || !lim.applies(v) || (v.Value >= lim.Lower && v.Value <= lim.Upper) {
+			continue
+		}
+
+		v.Reason = fmt.Sprintf("%s %g outside plausible limits %g-%g", p, v.Value, lim.Lower, lim.Upper)
+		v.Raw = strconv.FormatFloat(float64(v.Value), 'f', -1, 32)
+		v.Value = 0
+		v.Status = StatusRejected
+		v.Flag = FlagInvalid
+		res.Values[p] = v
+	}
+}
+
+// ApplyMeasuringRange replaces the values of res outside limits in their units
+// by the limit they exceed, with the comparator ">" or "<". The exported value
+// is kept in Raw.
+func ApplyMeasuringRange(res *Result, limits Limits) {
+	for p, v := range res.Values {
+		lim, ok := limits[p]
+		if !ok || !v.Valid() || !lim.applies(v) {
+			continue
+		}
+
+		switch {
+		case v.Value > lim.Upper:
+			v.Status = StatusAboveRange
+			v.Comparator = ">"
+			v.Reason = fmt.Sprintf("above measuring range %g-%g", lim.Lower, lim.Upper)
+			v.Raw = strconv.FormatFloat(float64(v.Value), 'f', -1, 32)
+			v.Value = lim.Upper
+		case v.Value < lim.Lower:
+			v.Status = StatusBelowRange
+			v.Comparator = "<"
+			v.Reason = fmt.Sprintf("below measuring range %g-%g", lim.Lower, lim.Upper)
+			v.Raw = strconv.FormatFloat(float64(v.Value), 'f', -1, 32)
+			v.Value = lim.Lower
+		default:
+			continue
+		}
+		res.Values[p] = v
+	}
+}
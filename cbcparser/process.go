@@ -0,0 +1,60 @@
+package cbcparser
+
+// Postprocess runs the checks configured in o on a result built by a
+// machine reader. Machine packages call it for every record they return.
+func (o *Options) Postprocess(res *Result) {
+	if o.InstrumentSerial != "" && res.Instrument.Serial == "" {
+		res.Instrument.Serial = o.InstrumentSerial
+	}
+
+	if o.Patients != nil {
+		if p := o.Patients(res); p != nil {
+			p.complete(res)
+		}
+	}
+
+	if o.Plausibility != nil {
+		CheckPlausibility(res, o.Plausibility)
+	}
+
+	if limits, ok := o.measuring_range(res.Instrument); ok {
+		ApplyMeasuringRange(res, limits)
+	}
+
+	ranges := o.ReferenceRanges
+	if o.RangeSelector != nil {
+		if rr := o.RangeSelector.Select(res); rr != nil {
+			ranges = rr
+		}
+	}
+
+	if ranges != nil {
+		ranges.Apply(res)
+	} else if res.RecordType == RecordPatient && !IsHuman(res.Species) {
+		// The normal ranges passed to the parsers are for humans.
+		NoRanges(res)
+	}
+
+	// Alerts are sent by the callers once the whole file is read.
+	ApplyCriticalLimits(res)
+
+	// Blank, control and calibration runs are not expected to satisfy
+	// the relationships between indices.
+	if res.RecordType == RecordPatient && o.Tolerances != nil {
+		for _, alarm := range CheckConsistency(res, o.Tolerances) {
+			res.AddAlarm(alarm)
+		}
+	}
+}
+
+// measuring_range returns the measuring range of instrument, or of its model
+// when none was set for its serial number.
+func (o *Options) measuring_range(instrument Instrument) (Limits, bool) {
+	if limits, ok := o.MeasuringRanges[instrument]; ok {
+		return limits, true
+	}
+
+	instrument.Serial = ""
+	limits, ok := o.MeasuringRanges[instrument]
+	return limits, ok
+}
@@ -0,0 +1,246 @@
+// Package profiles reads reference range profiles of several instruments from a
+// single configuration file and selects the profile of each result by the serial
+// number or model of its instrument:
+//
+//	{
+//		"profiles": [
+//			{
+//				"name": "HumaCount lab 1",
+//				"version": "2026-03",
+//				"serial": "HC30-1234",
+//				"units": {"wbc": "10^9/L", "hgb": "g/dL"},
+//				"ranges": {"wbc": {"lower": 4, "upper": 10}, "hgb": {"lower": 12, "upper": 17}}
+//			},
+//			{
+//				"name": "Edan",
+//				"version": "3",
+//				"model": "Edan Pro 30",
+//				"units": {"wbc": "10^3/µL", "hgb": "g/dL"},
+//				"ranges": {"default": {...}, "partitions": [...]}
+//			}
+//		]
+//	}
+//
+// Ranges are in the format of cbcparser.ReadReferenceRanges. Every ranged parameter
+// must declare its units, and ranges are converted to the units of each value.
+//
+//	cfg, err := profiles.Read(f)
+//	results, err := parser.ParseMulti(r, nil, cbcparser.WithRangeSelector(cfg),
+//		cbcparser.WithInstrumentSerial("HC30-1234"))
+package profiles
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/abiiranathan/cbcparser/cbcparser"
+	"github.com/abiiranathan/cbcparser/cbcparser/units"
+)
+
+// AlarmNoProfile is the code of the alarm attached to results of an
+// instrument without a profile.
+const AlarmNoProfile = "NO_RANGE_PROFILE"
+
+var ErrMissingUnits = errors.New("missing units")
+
+// Profile holds the reference ranges of one instrument or model.
+type Profile struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+
+	// Instrument the profile applies to. A profile with a serial number
+	// only applies to that instrument(of Model, if set), otherwise to
+	// every instrument of Model.
+	Serial string `json:"serial,omitempty"`
+	Model  string `json:"model,omitempty"`
+
+	// Units of the ranges of each parameter.
+	Units  map[cbcparser.Parameter]string `json:"units"`
+	Ranges json.RawMessage                `json:"ranges"`
+
+	ranges *cbcparser.ReferenceRanges
+}
+
+// Config holds the profiles of all instruments of a lab.
+type Config struct {
+	Profiles []*Profile `json:"profiles"`
+}
+
+// Read reads and validates a profiles configuration.
+func Read(r io.Reader) (*Config, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(cfg.Profiles))
+	for i, p := range cfg.Profiles {
+		if p.Name == "" {
+			return nil, fmt.Errorf("profile %d: missing name", i+1)
+		}
+
+		if names[p.Name] {
+			return nil, fmt.Errorf("profile %q: duplicate name", p.Name)
+		}
+		names[p.Name] = true
+
+		if err := p.load(); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+	}
+	return &cfg, nil
+}
+
+// load parses the ranges of p and checks that each ranged parameter has valid units.
+func (p *Profile) load() error {
+	if p.Serial == "" && p.Model == "" {
+		return fmt.Errorf("missing serial or model")
+	}
+
+	rr, err := cbcparser.ReadReferenceRanges(bytes.NewReader(p.Ranges))
+	if err != nil {
+		return err
+	}
+
+	var errs cbcparser.RangeErrors
+	for _, param := range ranged(rr) {
+		unit, ok := p.Units[param]
+		if !ok {
+			errs = append(errs, &cbcparser.RangeError{Key: param.Key(), Err: ErrMissingUnits})
+			continue
+		}
+
+		if _, err := units.ParseFor(param, unit); err != nil {
+			errs = append(errs, &cbcparser.RangeError{Key: param.Key(), Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	p.ranges = rr
+	return nil
+}
+
+// Profile returns the profile of instrument: the profile of its serial
+// number or else of its model. It returns nil if there is none.
+func (cfg *Config) Profile(instrument cbcparser.Instrument) *Profile {
+	if instrument.Serial != "" {
+		for _, p := range cfg.Profiles {
+			if p.Serial == instrument.Serial && (p.Model == "" || strings.EqualFold(p.Model, instrument.Model)) {
+				return p
+			}
+		}
+	}
+
+	for _, p := range cfg.Profiles {
+		if p.Serial == "" && strings.EqualFold(p.Model, instrument.Model) {
+			return p
+		}
+	}
+	return nil
+}
+
+// Select returns the ranges of the profile of the instrument of res converted to
+// the units of its values, and records the profile in res.RangeProfile.
+// Results of instruments without a profile get an alarm and nil ranges.
+func (cfg *Config) Select(res *cbcparser.Result) *cbcparser.ReferenceRanges {
+	p := cfg.Profile(res.Instrument)
+	if p == nil {
+		res.AddAlarm(cbcparser.Alarm{
+			Code:        AlarmNoProfile,
+			Description: fmt.Sprintf("No range profile for %s %s", res.Instrument.Model, res.Instrument.Serial),
+			Severity:    cbcparser.SeverityWarning,
+			Source:      cbcparser.SourceRanges,
+		})
+		return nil
+	}
+
+	res.RangeProfile = &cbcparser.RangeProfile{Name: p.Name, Version: p.Version}
+	return p.convert(res)
+}
+
+// convert returns the ranges of p in the units of the values of res.
+// Ranges of parameters whose units cannot be converted are dropped
+// rather than compared with values in other units.
+func (p *Profile) convert(res *cbcparser.Result) *cbcparser.ReferenceRanges {
+	factors := make(map[cbcparser.Parameter]float64)
+	for param, v := range res.Values {
+		from, ok := p.Units[param]
+		if !ok {
+			continue
+		}
+
+		if f, err := units.Factor(param, from, v.Units); err == nil {
+			factors[param] = f
+		}
+	}
+	return convert_ranges(p.ranges, factors)
+}
+
+func convert_ranges(rr *cbcparser.ReferenceRanges, factors map[cbcparser.Parameter]float64) *cbcparser.ReferenceRanges {
+	out := &cbcparser.ReferenceRanges{
+		Default: convert_set(rr.Default, factors),
+	}
+
+	for _, pt := range rr.Partitions {
+		pt.Ranges = convert_set(pt.Ranges, factors)
+		out.Partitions = append(out.Partitions, pt)
+	}
+
+	if rr.Species != nil {
+		out.Species = make(map[string]*cbcparser.ReferenceRanges, len(rr.Species))
+		for name, s := range rr.Species {
+			out.Species[name] = convert_ranges(s, factors)
+		}
+	}
+	return out
+}
+
+func convert_set(set map[cbcparser.Parameter]cbcparser.NormalRange, factors map[cbcparser.Parameter]float64) map[cbcparser.Parameter]cbcparser.NormalRange {
+	out := make(map[cbcparser.Parameter]cbcparser.NormalRange, len(set))
+	for param, nr := range set {
+		if f, ok := factors[param]; ok {
+			out[param] = units.ConvertRange(nr, f)
+		}
+	}
+	return out
+}
+
+// ranged returns the parameters with a range anywhere in rr in report order.
+func ranged(rr *cbcparser.ReferenceRanges) []cbcparser.Parameter {
+	seen := make(map[cbcparser.Parameter]bool)
+
+	var collect func(rr *cbcparser.ReferenceRanges)
+	collect = func(rr *cbcparser.ReferenceRanges) {
+		for param := range rr.Default {
+			seen[param] = true
+		}
+
+		for _, pt := range rr.Partitions {
+			for param := range pt.Ranges {
+				seen[param] = true
+			}
+		}
+
+		for _, s := range rr.Species {
+			collect(s)
+		}
+	}
+	collect(rr)
+
+	var params []cbcparser.Parameter
+	for _, param := range cbcparser.Parameters() {
+		if seen[param] {
+			params = append(params, param)
+		}
+	}
+	return params
+}
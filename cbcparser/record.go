@@ -0,0 +1,38 @@
+package cbcparser
+
+import "errors"
+
+var ErrExcludedRecord = errors.New("cbc record type was not selected")
+
+// RecordType classifies the run that produced a record.
+type RecordType string
+
+const (
+	RecordPatient     RecordType = "patient"
+	RecordBlank       RecordType = "blank" // background count
+	RecordControl     RecordType = "control"
+	RecordCalibration RecordType = "calibration"
+	RecordStartup     RecordType = "startup"
+)
+
+// Keep reports whether records of type t were selected with WithRecordTypes.
+// Only patient records are kept by default.
+func (o *Options) Keep(t RecordType) bool {
+	if len(o.RecordTypes) == 0 {
+		return t == RecordPatient
+	}
+
+	for _, rt := range o.RecordTypes {
+		if rt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// WithRecordTypes selects the types of records returned by the parsers.
+func WithRecordTypes(types ...RecordType) Option {
+	return func(o *Options) {
+		o.RecordTypes = types
+	}
+}
@@ -0,0 +1,286 @@
+package cbcparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AlarmNoRanges is the code of the alarm attached to results of a species
+// without reference ranges.
+const AlarmNoRanges = "NO_REFERENCE_RANGES"
+
+// Age is a duration in calendar units e.g 28 days or 18 years,
+// written as "28d", "6w", "6m" or "18y" in JSON.
+type Age struct {
+	N    int
+	Unit byte // 'd', 'w', 'm' or 'y'
+}
+
+// IsZero reports whether a is unset.
+func (a Age) IsZero() bool {
+	return a.N == 0
+}
+
+// After returns the date when someone born on birth reaches age a.
+func (a Age) After(birth time.Time) time.Time {
+	switch a.Unit {
+	case 'w':
+		return birth.AddDate(0, 0, 7*a.N)
+	case 'm':
+		return birth.AddDate(0, a.N, 0)
+	case 'y':
+		return birth.AddDate(a.N, 0, 0)
+	}
+	return birth.AddDate(0, 0, a.N)
+}
+
+func (a Age) String() string {
+	if a.IsZero() {
+		return ""
+	}
+	return strconv.Itoa(a.N) + string(a.Unit)
+}
+
+func (a Age) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *Age) UnmarshalText(text []byte) error {
+	s := string(text)
+	if s == "" {
+		*a = Age{}
+		return nil
+	}
+
+	unit := s[len(s)-1]
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 || (unit != 'd' && unit != 'w' && unit != 'm' && unit != 'y') {
+		return fmt.Errorf("invalid age %q, expected e.g 28d, 6w, 6m or 18y", s)
+	}
+	*a = Age{N: n, Unit: unit}
+	return nil
+}
+
+// Partition holds the reference ranges of patients of one sex and age band.
+// An empty Sex matches both sexes and a zero MaxAge has no upper bound.
+type Partition struct {
+	Name   string                    `json:"name,omitempty"`
+	Sex    Sex                       `json:"sex,omitempty"`
+	MinAge Age                       `json:"min_age,omitempty"` // inclusive
+	MaxAge Age                       `json:"max_age,omitempty"` // exclusive
+	Ranges map[Parameter]NormalRange `json:"ranges"`
+}
+
+// Matches reports whether a patient of sex born on birth belongs to pt at time at.
+// Partitions limited by sex or age never match patients whose sex or age is unknown.
+func (pt *Partition) Matches(sex Sex, birth *time.Time, at time.Time) bool {
+	if pt.Sex != SexUnknown && pt.Sex != sex {
+		return false
+	}
+
+	if pt.MinAge.IsZero() && pt.MaxAge.IsZero() {
+		return true
+	}
+
+	if birth == nil {
+		return false
+	}
+
+	if !pt.MinAge.IsZero() && at.Before(pt.MinAge.After(*birth)) {
+		return false
+	}
+	return pt.MaxAge.IsZero() || at.Before(pt.MaxAge.After(*birth))
+}
+
+// ReferenceRanges are reference ranges partitioned by sex and age.
+//
+//	{
+//		"partitions": [
+//			{"name": "neonate", "max_age": "28d", "ranges": {"hgb": {"lower": 13.5, "upper": 21.5}}},
+//			{"name": "adult female", "sex": "female", "min_age": "18y", "ranges": {"hgb": {"lower": 12, "upper": 15.5}}}
+//		],
+//		"default": {"hgb": {"lower": 13, "upper": 17}}
+//	}
+//
+// The range of a parameter comes from the first matching partition that
+// has one, or from Default.
+//
+// Veterinary results are matched against the ranges of their species,
+// keyed case-insensitively by the profile exported by the machine:
+//
+//	{"default": {...}, "species": {"dog": {"default": {...}}, "cat": {"partitions": [...]}}}
+//
+// Ranges outside Species apply to human results.
+type ReferenceRanges struct {
+	Partitions []Partition                 `json:"partitions"`
+	Default    map[Parameter]NormalRange   `json:"default"`
+	Species    map[string]*ReferenceRanges `json:"species,omitempty"`
+}
+
+// NewReferenceRanges returns reference ranges with nr as the default for all patients.
+// Parameters without a range in nr have no default.
+func NewReferenceRanges(nr *CBCNormalRange) *ReferenceRanges {
+	rr := &ReferenceRanges{Default: make(map[Parameter]NormalRange)}
+	for _, p := range Parameters() {
+		if r := nr.Range(p); !r.IsZero() {
+			rr.Default[p] = r
+		}
+	}
+	return rr
+}
+
+// ReadReferenceRanges reads and validates reference ranges in the partitioned
+// format or in the flat format of ReadNormalRanges. Unknown keys and fields and
+// invalid ranges, partitions and species are reported together as RangeErrors.
+func ReadReferenceRanges(r io.Reader) (*ReferenceRanges, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+
+	_, partitioned := keys["partitions"]
+	_, hasDefault := keys["default"]
+	_, hasSpecies := keys["species"]
+	if !partitioned && !hasDefault && !hasSpecies {
+		nr, err := ReadNormalRanges(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return NewReferenceRanges(nr), nil
+	}
+
+	// Unknown keys and fields are removed so that the remaining ranges
+	// are validated and all problems are reported together.
+	cleaned, errs := clean_reference(data, "")
+
+	var rr ReferenceRanges
+	if err := json.Unmarshal(cleaned, &rr); err != nil {
+		return nil, err
+	}
+
+	errs = append(errs, rr.validate("")...)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return &rr, nil
+}
+
+// WithReferenceRanges selects the range of every value from rr using the sex
+// and age of the patient at the time of analysis, and recomputes the flags.
+// It takes precedence over the normal ranges passed to the parser.
+func WithReferenceRanges(rr *ReferenceRanges) Option {
+	return func(o *Options) {
+		o.ReferenceRanges = rr
+	}
+}
+
+// RangeSelector selects the reference ranges of a result e.g from the profile of
+// its instrument(see package profiles). Select returns nil if it has no ranges for
+// res and may record the profile it selected in res.RangeProfile.
+type RangeSelector interface {
+	Select(res *Result) *ReferenceRanges
+}
+
+// WithRangeSelector sets the selector of the ranges of each result.
+func WithRangeSelector(s RangeSelector) Option {
+	return func(o *Options) {
+		o.RangeSelector = s
+	}
+}
+
+// Range returns the range of p for a patient of sex born on birth at time at.
+func (rr *ReferenceRanges) Range(p Parameter, sex Sex, birth *time.Time, at time.Time) (NormalRange, bool) {
+	for i := range rr.Partitions {
+		pt := &rr.Partitions[i]
+		if nr, ok := pt.Ranges[p]; ok && pt.Matches(sex, birth, at) {
+			return nr, true
+		}
+	}
+
+	nr, ok := rr.Default[p]
+	return nr, ok
+}
+
+// IsHuman reports whether species, as exported by the machine, is human.
+func IsHuman(species string) bool {
+	return species == "" || strings.EqualFold(species, "human")
+}
+
+// ForSpecies returns the ranges of species or nil if there are none.
+func (rr *ReferenceRanges) ForSpecies(species string) *ReferenceRanges {
+	for name, ranges := range rr.Species {
+		if strings.EqualFold(name, species) {
+			return ranges
+		}
+	}
+
+	if IsHuman(species) {
+		return rr
+	}
+	return nil
+}
+
+// Apply sets the ranges of the values of res for its species and patient and
+// recomputes their flags. Values flagged invalid or suspect keep their flag,
+// and values without a range are unchanged.
+//
+// When there are no ranges for the species of res, the ranges of its values
+// are cleared and an alarm is attached instead.
+func (rr *ReferenceRanges) Apply(res *Result) {
+	ranges := rr.ForSpecies(res.Species)
+	if ranges == nil {
+		NoRanges(res)
+		return
+	}
+	ranges.apply(res)
+}
+
+// NoRanges clears the ranges of the values of res and attaches an alarm
+// that there are no reference ranges for its species. Flags exported
+// by the machine are kept.
+func NoRanges(res *Result) {
+	for p, v := range res.Values {
+		v.NormalRange = NormalRange{}
+		if v.RawFlag == "" && v.Flag != FlagInvalid && v.Flag != FlagSuspect {
+			v.Flag = FlagNone
+		}
+		res.Values[p] = v
+	}
+
+	res.AddAlarm(Alarm{
+		Code:        AlarmNoRanges,
+		Description: fmt.Sprintf("No reference ranges for species %q", res.Species),
+		Severity:    SeverityWarning,
+		Source:      SourceRanges,
+	})
+}
+
+func (rr *ReferenceRanges) apply(res *Result) {
+	at := time.Now()
+	if res.Timestamp != nil {
+		at = *res.Timestamp
+	}
+
+	for p, v := range res.Values {
+		nr, ok := rr.Range(p, res.Sex, res.BirthDate, at)
+		if !ok {
+			continue
+		}
+
+		v.NormalRange = nr
+		if (v.Valid() || v.Comparator != "") && v.Flag != FlagInvalid && v.Flag != FlagSuspect {
+			v.Flag = FlagFor(v.Value, nr)
+		}
+		res.Values[p] = v
+	}
+}
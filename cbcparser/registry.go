@@ -0,0 +1,113 @@
+package cbcparser
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+)
+
+var ErrUnknownFormat = errors.New("unknown cbc file format")
+
+// Detector reports whether header(the first line of an export)
+// was produced by the machine of a registered parser.
+type Detector func(header string) bool
+
+type driver struct {
+	detect Detector
+	parser CSVMultiParser
+}
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]driver)
+)
+
+// Register makes a parser available to Detect and ParseAuto under name.
+// Machine packages register themselves when imported, in the same way as
+// database/sql drivers:
+//
+//	import _ "github.com/abiiranathan/cbcparser/cbcparser/human"
+//
+// Register panics if called twice with the same name or with a nil detector or parser.
+func Register(name string, detect Detector, parser CSVMultiParser) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if detect == nil || parser == nil {
+		panic("cbcparser: Register detector or parser is nil")
+	}
+
+	if _, dup := drivers[name]; dup {
+		panic("cbcparser: Register called twice for " + name)
+	}
+	drivers[name] = driver{detect: detect, parser: parser}
+}
+
+// Drivers returns the sorted names of the registered parsers.
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// maxHeaderSize limits how much of a file is read while sniffing the header line.
+const maxHeaderSize = 64 * 1024
+
+// Detect sniffs the header line of r and returns the name of the matching parser.
+// The returned reader yields the full contents of r including the header,
+// transcoded to UTF-8 from the encoding set in opts or detected, and must be
+// used in place of r.
+func Detect(r io.Reader, opts ...Option) (string, io.Reader, error) {
+	r, _, err := NewUTF8Reader(r, NewOptions(opts...).Encoding)
+	if err != nil {
+		return "", nil, err
+	}
+
+	br := bufio.NewReaderSize(r, maxHeaderSize)
+	data, err := br.Peek(maxHeaderSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return "", br, err
+	}
+
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[:i]
+	}
+	header := string(bytes.TrimRight(data, "\r"))
+
+	for _, name := range Drivers() {
+		driversMu.RLock()
+		d := drivers[name]
+		driversMu.RUnlock()
+
+		if d.detect(header) {
+			return name, br, nil
+		}
+	}
+	return "", br, ErrUnknownFormat
+}
+
+// ParseAuto detects the machine that produced r and parses all records
+// with the registered parser.
+func ParseAuto(r io.Reader, normal_ranges *CBCNormalRange, opts ...Option) (Results, error) {
+	name, r, err := Detect(r, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	driversMu.RLock()
+	d := drivers[name]
+	driversMu.RUnlock()
+
+	// r is already transcoded by Detect.
+	opts = append(opts[:len(opts):len(opts)], WithEncoding(UTF8))
+	return d.parser.ParseMulti(r, normal_ranges, opts...)
+}
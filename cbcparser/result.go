@@ -0,0 +1,198 @@
+package cbcparser
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Instrument identifies the machine that produced a result.
+type Instrument struct {
+	Manufacturer string `json:"manufacturer"`
+	Model        string `json:"model"`
+	Serial       string `json:"serial,omitempty"` // See WithInstrumentSerial
+}
+
+// RangeProfile identifies the reference range profile applied to a result.
+type RangeProfile struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// SampleType is the type of sample analysed.
+type SampleType string
+
+const (
+	WholeBlood SampleType = "whole_blood"
+	Prediluted SampleType = "prediluted"
+	Capillary  SampleType = "capillary"
+)
+
+// Result is the machine-neutral representation of a single CBC record.
+// Every parser returns a Result so that downstream code can treat
+// all supported machines identically.
+type Result struct {
+	SampleID   string     `json:"sample_id"`
+	PatientID  string     `json:"patient_id"`
+	Instrument Instrument `json:"instrument"`
+	RecordType RecordType `json:"record_type"`
+
+	// Date and time of analysis or nil if the machine did not export it.
+	Timestamp *time.Time `json:"timestamp"`
+	// Date of birth of the patient or nil if unknown.
+	BirthDate *time.Time `json:"birth_date"`
+	// Sex of the patient, see WithPatients.
+	Sex Sex `json:"sex"`
+	// Species or profile the machine analysed the sample with
+	// e.g "Human" or "Dog". Empty if the machine does not report it
+	// and for blank, control and calibration runs.
+	Species string `json:"species"`
+
+	// Type of sample or empty if the machine does not report it.
+	// Prediluted samples may need different ranges.
+	SampleType SampleType `json:"sample_type"`
+
+	// Measured analytes keyed by parameter.
+	Values map[Parameter]CBCValue `json:"values"`
+
+	// Profile of the reference ranges or nil if they were not selected
+	// by a RangeSelector.
+	RangeProfile *RangeProfile `json:"range_profile,omitempty"`
+
+	// Alarms raised by the machine or by checks run on the result.
+	Alarms []Alarm `json:"alarms"`
+
+	// Values derived from the measured values e.g by package indices.
+	Calculated []CalculatedValue `json:"calculated,omitempty"`
+
+	// Machine-specific view of the record(e.g human.HumanCBCResult) as
+	// exported, before Options.Postprocess. Its values, ranges and flags
+	// do not reflect plausibility checks, reference ranges or critical
+	// limits; use Values for those.
+	Raw CBCWriter `json:"-"`
+}
+
+// Results is a list of parsed CBC records.
+type Results []*Result
+
+// NewResult returns an empty result for the given instrument.
+func NewResult(instrument Instrument) *Result {
+	return &Result{
+		Instrument: instrument,
+		Values:     make(map[Parameter]CBCValue),
+	}
+}
+
+// Value returns the value of parameter p and whether it was reported.
+func (res *Result) Value(p Parameter) (CBCValue, bool) {
+	v, ok := res.Values[p]
+	return v, ok
+}
+
+// Set stores the value of parameter p.
+// The LOINC code of p is added to v if it has none.
+func (res *Result) Set(p Parameter, v CBCValue) {
+	if res.Values == nil {
+		res.Values = make(map[Parameter]CBCValue)
+	}
+
+	if v.LOINC == nil {
+		v.LOINC = p.LOINC()
+	}
+	res.Values[p] = v
+}
+
+// Parameters returns the parameters reported in res in report order.
+func (res *Result) Parameters() []Parameter {
+	var params []Parameter
+	for _, p := range Parameters() {
+		if _, ok := res.Values[p]; ok {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+func (res *Result) Write(out io.Writer, format OutFormat) error {
+	return write(out, res, format)
+}
+
+func (list Results) Write(out io.Writer, format OutFormat) error {
+	return write(out, list, format)
+}
+
+// Range returns the normal range configured for parameter p.
+func (nr *CBCNormalRange) Range(p Parameter) NormalRange {
+	switch p {
+	case WBC:
+		return nr.WBC
+	case LYM:
+		return nr.LYM
+	case MID:
+		return nr.MID
+	case GRA:
+		return nr.GRA
+	case LYMPercent:
+		return nr.LYMPercent
+	case MIDPercent:
+		return nr.MIDPercent
+	case GRAPercent:
+		return nr.GRAPercent
+	case RBC:
+		return nr.RBC
+	case HGB:
+		return nr.HGB
+	case HCT:
+		return nr.HCT
+	case MCV:
+		return nr.MCV
+	case MCH:
+		return nr.MCH
+	case MCHC:
+		return nr.MCHC
+	case RDWs:
+		return nr.RDWs
+	case RDWc:
+		return nr.RDWc
+	case PLT:
+		return nr.PLT
+	case PCT:
+		return nr.PCT
+	case MPV:
+		return nr.MPV
+	case PDW:
+		return nr.PDW
+	case PDWs:
+		return nr.PDWs
+	case PDWc:
+		return nr.PDWc
+	case PLCC:
+		return nr.PLCC
+	case PLCR:
+		return nr.PLCR
+	}
+	return NormalRange{}
+}
+
+func write(out io.Writer, v interface{}, format OutFormat) error {
+	var data []byte
+
+	if format == JSON {
+		d, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = d
+	} else if format == JSONIndent {
+		d, err := json.MarshalIndent(v, "", "	")
+		if err != nil {
+			return err
+		}
+		data = d
+	} else {
+		return ErrInvalidOutFormat
+	}
+
+	_, err := out.Write(data)
+	return err
+}
@@ -0,0 +1,251 @@
+package cbcparser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"io"
+)
+
+// RowReader reads the header and data rows of a machine export and
+// keeps track of their position for error reporting.
+// Machine packages use it to implement RecordReader.
+type RowReader struct {
+	Columns  *Columns // Columns of the header, set after the first call to Read
+	Options  *Options
+	Encoding Encoding // Encoding of the export
+
+	// Validate is called with the columns of the header when it is read.
+	// Its error is returned as a *ParseError for the header line.
+	Validate func(columns *Columns) error
+
+	// Classify returns the type of the record in row and Build converts
+	// row into a result. Machine packages set both before calling Next or First.
+	Classify func(row []string) RecordType
+	Build    func(row []string) (*Result, error)
+
+	reader   *csv.Reader
+	split    func(header string) (label, units string)
+	required []string
+	rows     int
+	err      error // sticky error while reading the header
+	errs     RowErrors
+}
+
+// NewRowReader returns a reader for the rows of r.
+// The caller sets Classify and Build before reading records.
+// r is transcoded to UTF-8 from the encoding set in opts or detected from its contents.
+// Field and decimal separators not set in opts are detected from the header and
+// first row, falling back to separator as the field separator.
+// split separates headers into labels and units and required lists the
+// labels of columns that must be present in the header.
+func NewRowReader(r io.Reader, separator rune, split func(string) (string, string), required []string, opts *Options) *RowReader {
+	rr := &RowReader{
+		Options:  opts,
+		split:    split,
+		required: required,
+	}
+
+	utf8, enc, err := NewUTF8Reader(r, opts.Encoding)
+	if err != nil {
+		rr.err = err
+		utf8 = r
+	}
+	rr.Encoding = enc
+
+	br := bufio.NewReaderSize(utf8, sniffSize)
+	sample, _ := br.Peek(sniffSize)
+	lines := bytes.SplitN(sample, []byte("\n"), 3)
+	if len(lines) > 1 {
+		field, decimal := DetectSeparators(string(lines[0]), string(lines[1]), separator)
+		if opts.FieldSeparator == 0 {
+			opts.FieldSeparator = field
+		}
+
+		if opts.DecimalSeparator == 0 {
+			opts.DecimalSeparator = decimal
+		}
+	}
+
+	if opts.FieldSeparator == 0 {
+		opts.FieldSeparator = separator
+	}
+
+	rr.reader = csv.NewReader(br)
+	rr.reader.Comma = opts.FieldSeparator
+	rr.reader.FieldsPerRecord = 0 // same as the header
+	rr.reader.ReuseRecord = true
+	return rr
+}
+
+// Read returns the next data row, reading the header on the first call.
+// The returned row is only valid until the next call to Read.
+//
+// Errors in a data row are returned as *ParseError and reading may continue
+// with the next row. Errors in the header are returned on every call.
+func (rr *RowReader) Read() ([]string, error) {
+	if rr.err != nil {
+		return nil, rr.err
+	}
+
+	if rr.Columns == nil {
+		if err := rr.read_header(); err != nil {
+			rr.err = err
+			return nil, err
+		}
+	}
+
+	row, err := rr.reader.Read()
+	if err == io.EOF {
+		if rr.rows == 0 {
+			return nil, ErrInsufficientRows
+		}
+		return nil, io.EOF
+	}
+
+	rr.rows++
+	if err != nil {
+		return nil, NewParseError(rr.Options.FileName, err)
+	}
+	return row, nil
+}
+
+func (rr *RowReader) read_header() error {
+	headers, err := rr.reader.Read()
+	if err == io.EOF {
+		return ErrInsufficientRows
+	}
+
+	if err != nil {
+		return NewParseError(rr.Options.FileName, err)
+	}
+
+	columns := NewColumns(headers, rr.split)
+	if err := columns.Require(rr.required...); err != nil {
+		line, _ := rr.reader.FieldPos(0)
+		return &ParseError{File: rr.Options.FileName, Line: line, Column: 1, Err: err}
+	}
+
+	if rr.Validate != nil {
+		if err := rr.Validate(columns); err != nil {
+			return rr.header_error(columns, err)
+		}
+	}
+	rr.Columns = columns
+	return nil
+}
+
+func (rr *RowReader) header_error(columns *Columns, err error) *ParseError {
+	pe, ok := err.(*ParseError)
+	if !ok {
+		pe = &ParseError{Err: err}
+	}
+
+	pe.File = rr.Options.FileName
+	i, ok := columns.Index(pe.Field)
+	if !ok {
+		i = 0
+	}
+	pe.Line, pe.Column = rr.reader.FieldPos(i)
+	return pe
+}
+
+// FieldError returns a *ParseError for the cell under label in the last row read.
+func (rr *RowReader) FieldError(row []string, label string, err error) *ParseError {
+	i, _ := rr.Columns.Index(label)
+	return NewFieldError(rr.Options.FileName, rr.reader, i, label, rr.Columns.Get(row, label), err)
+}
+
+// Skip reports whether err belongs to a single row that should be skipped
+// because of SkipBadRows. Skipped errors are available from Errors.
+func (rr *RowReader) Skip(err error) bool {
+	pe, ok := err.(*ParseError)
+	if !ok || rr.err != nil || !rr.Options.SkipBadRows {
+		return false
+	}
+	rr.errs = append(rr.errs, pe)
+	return true
+}
+
+// Errors returns the errors of the rows skipped so far.
+func (rr *RowReader) Errors() RowErrors {
+	return rr.errs
+}
+
+// Next returns the next record of a type kept by Options, or io.EOF at the end
+// of the file. Results are post-processed(see Options.Postprocess).
+// With SkipBadRows, rows that cannot be parsed are skipped and reported by Errors.
+func (rr *RowReader) Next() (*Result, error) {
+	for {
+		res, err := rr.next()
+		if err != nil && rr.Skip(err) {
+			continue
+		}
+		return res, err
+	}
+}
+
+func (rr *RowReader) next() (*Result, error) {
+	for {
+		row, err := rr.Read()
+		if err != nil {
+			return nil, err
+		}
+
+		if !rr.Options.Keep(rr.Classify(row)) {
+			continue
+		}
+		return rr.build(row)
+	}
+}
+
+// First returns the record of the first data row. It returns ErrBlankCBCRecord
+// or ErrExcludedRecord if the type of the record is not kept by Options.
+func (rr *RowReader) First() (*Result, error) {
+	row, err := rr.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	if rt := rr.Classify(row); !rr.Options.Keep(rt) {
+		if rt == RecordBlank {
+			return nil, ErrBlankCBCRecord
+		}
+		return nil, ErrExcludedRecord
+	}
+
+	res, err := rr.build(row)
+	if err != nil {
+		return nil, err
+	}
+
+	Alert(Results{res}, rr.Options.Alerters...)
+	return res, nil
+}
+
+func (rr *RowReader) build(row []string) (*Result, error) {
+	res, err := rr.Build(row)
+	if err != nil {
+		return nil, err
+	}
+
+	rr.Options.Postprocess(res)
+	return res, nil
+}
+
+// ReadAll reads all remaining records. When rows were skipped because
+// of SkipBadRows, the records are returned together with RowErrors.
+// The alerters of Options are invoked once all records were read.
+func (rr *RowReader) ReadAll() (Results, error) {
+	results, err := ReadAll(rr)
+	if err != nil {
+		return nil, err
+	}
+
+	Alert(results, rr.Options.Alerters...)
+
+	if len(rr.errs) > 0 {
+		return results, rr.errs
+	}
+	return results, nil
+}
@@ -0,0 +1,114 @@
+package units
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/abiiranathan/cbcparser/cbcparser"
+)
+
+// System maps parameters to the units they should be reported in.
+// Parameters not in the map are left unchanged. A per-analyte map
+// can be used to build a lab specific system.
+type System map[cbcparser.Parameter]string
+
+// SI units.
+var SI = System{
+	cbcparser.WBC:        "10^9/L",
+	cbcparser.LYM:        "10^9/L",
+	cbcparser.MID:        "10^9/L",
+	cbcparser.GRA:        "10^9/L",
+	cbcparser.LYMPercent: "%",
+	cbcparser.MIDPercent: "%",
+	cbcparser.GRAPercent: "%",
+	cbcparser.RBC:        "10^12/L",
+	cbcparser.HGB:        "g/L",
+	cbcparser.HCT:        "L/L",
+	cbcparser.MCV:        "fL",
+	cbcparser.MCH:        "pg",
+	cbcparser.MCHC:       "g/L",
+	cbcparser.RDWs:       "fL",
+	cbcparser.RDWc:       "%",
+	cbcparser.PLT:        "10^9/L",
+	cbcparser.PCT:        "%",
+	cbcparser.MPV:        "fL",
+	cbcparser.PDW:        "fL",
+	cbcparser.PDWs:       "fL",
+	cbcparser.PDWc:       "%",
+	cbcparser.PLCC:       "10^9/L",
+	cbcparser.PLCR:       "%",
+}
+
+// Conventional(US) units.
+var Conventional = System{
+	cbcparser.WBC:        "10^3/µL",
+	cbcparser.LYM:        "10^3/µL",
+	cbcparser.MID:        "10^3/µL",
+	cbcparser.GRA:        "10^3/µL",
+	cbcparser.LYMPercent: "%",
+	cbcparser.MIDPercent: "%",
+	cbcparser.GRAPercent: "%",
+	cbcparser.RBC:        "10^6/µL",
+	cbcparser.HGB:        "g/dL",
+	cbcparser.HCT:        "%",
+	cbcparser.MCV:        "fL",
+	cbcparser.MCH:        "pg",
+	cbcparser.MCHC:       "g/dL",
+	cbcparser.RDWs:       "fL",
+	cbcparser.RDWc:       "%",
+	cbcparser.PLT:        "10^3/µL",
+	cbcparser.PCT:        "%",
+	cbcparser.MPV:        "fL",
+	cbcparser.PDW:        "fL",
+	cbcparser.PDWs:       "fL",
+	cbcparser.PDWc:       "%",
+	cbcparser.PLCC:       "10^3/µL",
+	cbcparser.PLCR:       "%",
+}
+
+// Lookup returns the system named "SI" or "conventional"(case-insensitive).
+func Lookup(name string) (System, error) {
+	switch strings.ToLower(name) {
+	case "si":
+		return SI, nil
+	case "conventional":
+		return Conventional, nil
+	}
+	return nil, fmt.Errorf("unknown unit system %q", name)
+}
+
+// ConvertResult converts the values of res to the units of system.
+// Values that cannot be converted are left unchanged and reported in the error.
+func ConvertResult(res *cbcparser.Result, system System) error {
+	var failed []string
+	for _, p := range res.Parameters() {
+		to, ok := system[p]
+		if !ok {
+			continue
+		}
+
+		v, err := Convert(p, res.Values[p], to)
+		if err != nil {
+			failed = append(failed, p.String()+": "+err.Error())
+			continue
+		}
+		res.Set(p, v)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("sample %s: %s", res.SampleID, strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// ConvertResults converts the values of every result in list.
+// It returns the first error after converting all results.
+func ConvertResults(list cbcparser.Results, system System) error {
+	var first error
+	for _, res := range list {
+		if err := ConvertResult(res, system); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
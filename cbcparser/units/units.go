@@ -0,0 +1,222 @@
+// Package units understands the unit strings exported by the machines and
+// converts CBC values between conventional and SI units.
+package units
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/abiiranathan/cbcparser/cbcparser"
+)
+
+// Dimension is the kind of quantity a unit measures.
+type Dimension int
+
+const (
+	NumberConcentration    Dimension = iota + 1 // cells per volume
+	MassConcentration                           // e.g hemoglobin per volume
+	SubstanceConcentration                      // e.g hemoglobin(Fe) per volume
+	Fraction                                    // e.g HCT, LYM%
+	Volume                                      // e.g MCV
+	Mass                                        // e.g MCH
+	Amount                                      // e.g MCH in fmol
+)
+
+var (
+	ErrUnknownUnit    = errors.New("unknown unit")
+	ErrUnexpectedUnit = errors.New("unexpected unit")
+)
+
+// Unit is a recognised unit of measure.
+type Unit struct {
+	Symbol    string    // Display symbol e.g 10^9/L
+	UCUM      string    // UCUM code e.g 10*9/L
+	Dimension Dimension // Kind of quantity
+	Factor    float64   // Multiplier to the base unit of the dimension
+}
+
+// Known units with their accepted spellings. The base unit of each
+// dimension has a factor of 1.
+var known = []struct {
+	unit    Unit
+	aliases []string
+}{
+	{Unit{"10^9/L", "10*9/L", NumberConcentration, 1}, []string{"10*9/L", "10E9/L", "x10^9/L", "G/L", "/nL"}},
+	{Unit{"10^3/µL", "10*3/uL", NumberConcentration, 1}, []string{"10*3/uL", "10E3/uL", "x10^3/uL", "K/uL", "10^3/mm^3"}},
+	{Unit{"10^12/L", "10*12/L", NumberConcentration, 1000}, []string{"10*12/L", "10E12/L", "x10^12/L", "T/L", "/pL"}},
+	{Unit{"10^6/µL", "10*6/uL", NumberConcentration, 1000}, []string{"10*6/uL", "10E6/uL", "x10^6/uL", "M/uL", "10^6/mm^3"}},
+	{Unit{"/µL", "/uL", NumberConcentration, 0.001}, []string{"/uL", "cells/uL", "/mm^3"}},
+	{Unit{"g/L", "g/L", MassConcentration, 1}, nil},
+	{Unit{"g/dL", "g/dL", MassConcentration, 10}, []string{"g%"}},
+	{Unit{"mmol/L", "mmol/L", SubstanceConcentration, 1}, nil},
+	{Unit{"L/L", "L/L", Fraction, 1}, []string{"1"}},
+	{Unit{"%", "%", Fraction, 0.01}, nil},
+	{Unit{"fL", "fL", Volume, 1}, []string{"um^3", "um3"}},
+	{Unit{"pg", "pg", Mass, 1}, nil},
+	{Unit{"fmol", "fmol", Amount, 1}, nil},
+}
+
+// Hemoglobin(monomer, Fe) in mmol per g, used between mass and substance units.
+const hemoglobinMmolPerGram = 1 / 16.1145
+
+// normalize folds the spelling differences of exported units e.g 10^3/μL and 10*3/ul.
+func normalize(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.NewReplacer("µ", "u", "μ", "u", "*", "^", " ", "").Replace(s)
+	return strings.ToLower(s)
+}
+
+var index = make(map[string]Unit)
+
+func init() {
+	for _, k := range known {
+		index[normalize(k.unit.Symbol)] = k.unit
+		index[normalize(k.unit.UCUM)] = k.unit
+		for _, alias := range k.aliases {
+			index[normalize(alias)] = k.unit
+		}
+	}
+}
+
+// Parse returns the unit spelled by s e.g "10^9/l", "10^3/μL" or "g/dl".
+func Parse(s string) (Unit, error) {
+	u, ok := index[normalize(s)]
+	if !ok {
+		return Unit{}, fmt.Errorf("%w %q", ErrUnknownUnit, s)
+	}
+	return u, nil
+}
+
+// Dimensions each parameter may be reported in.
+var dimensions = map[cbcparser.Parameter][]Dimension{
+	cbcparser.WBC:        {NumberConcentration},
+	cbcparser.LYM:        {NumberConcentration},
+	cbcparser.MID:        {NumberConcentration},
+	cbcparser.GRA:        {NumberConcentration},
+	cbcparser.LYMPercent: {Fraction},
+	cbcparser.MIDPercent: {Fraction},
+	cbcparser.GRAPercent: {Fraction},
+	cbcparser.RBC:        {NumberConcentration},
+	cbcparser.HGB:        {MassConcentration, SubstanceConcentration},
+	cbcparser.HCT:        {Fraction},
+	cbcparser.MCV:        {Volume},
+	cbcparser.MCH:        {Mass, Amount},
+	cbcparser.MCHC:       {MassConcentration, SubstanceConcentration},
+	cbcparser.RDWs:       {Volume},
+	cbcparser.RDWc:       {Fraction},
+	cbcparser.PLT:        {NumberConcentration},
+	cbcparser.PCT:        {Fraction},
+	cbcparser.MPV:        {Volume},
+	cbcparser.PDW:        {Volume, Fraction},
+	cbcparser.PDWs:       {Volume},
+	cbcparser.PDWc:       {Fraction},
+	cbcparser.PLCC:       {NumberConcentration},
+	cbcparser.PLCR:       {Fraction},
+}
+
+// ParseFor returns the unit spelled by s and checks that it is a valid unit for parameter p.
+func ParseFor(p cbcparser.Parameter, s string) (Unit, error) {
+	u, err := Parse(s)
+	if err != nil {
+		return u, err
+	}
+
+	for _, d := range dimensions[p] {
+		if u.Dimension == d {
+			return u, nil
+		}
+	}
+	return u, fmt.Errorf("%w %q for %s", ErrUnexpectedUnit, s, p)
+}
+
+// UCUM returns the UCUM code of the unit spelled by s e.g "10*9/L" for "10^9/l".
+func UCUM(s string) (string, error) {
+	u, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return u.UCUM, nil
+}
+
+// Equal reports whether a and b are spellings of the same unit.
+func Equal(a, b string) bool {
+	ua, err := Parse(a)
+	if err != nil {
+		return false
+	}
+
+	ub, err := Parse(b)
+	return err == nil && ua == ub
+}
+
+// Factor returns the number by which a value of parameter p in unit from
+// must be multiplied to express it in unit to.
+func Factor(p cbcparser.Parameter, from, to string) (float64, error) {
+	fu, err := Parse(from)
+	if err != nil {
+		return 0, err
+	}
+
+	tu, err := Parse(to)
+	if err != nil {
+		return 0, err
+	}
+
+	if fu.Dimension == tu.Dimension {
+		return fu.Factor / tu.Factor, nil
+	}
+
+	// Hemoglobin is reported in mass or substance units.
+	var molar float64
+	switch {
+	case fu.Dimension == MassConcentration && tu.Dimension == SubstanceConcentration,
+		fu.Dimension == Mass && tu.Dimension == Amount:
+		molar = hemoglobinMmolPerGram
+	case fu.Dimension == SubstanceConcentration && tu.Dimension == MassConcentration,
+		fu.Dimension == Amount && tu.Dimension == Mass:
+		molar = 1 / hemoglobinMmolPerGram
+	}
+
+	if molar != 0 && (p == cbcparser.HGB || p == cbcparser.MCH || p == cbcparser.MCHC) {
+		return fu.Factor * molar / tu.Factor, nil
+	}
+	return 0, fmt.Errorf("cannot convert %s from %s to %s", p, from, to)
+}
+
+// ConvertRange multiplies the limits of nr by factor(see Factor).
+func ConvertRange(nr cbcparser.NormalRange, factor float64) cbcparser.NormalRange {
+	scale := func(x float32) float32 {
+		return float32(float64(x) * factor)
+	}
+
+	return cbcparser.NormalRange{
+		Lower:         scale(nr.Lower),
+		Upper:         scale(nr.Upper),
+		CriticalLower: scale(nr.CriticalLower),
+		CriticalUpper: scale(nr.CriticalUpper),
+	}
+}
+
+// Convert returns v of parameter p expressed in unit to.
+// The value and normal range are converted together.
+func Convert(p cbcparser.Parameter, v cbcparser.CBCValue, to string) (cbcparser.CBCValue, error) {
+	if v.Units == to {
+		return v, nil
+	}
+
+	factor, err := Factor(p, v.Units, to)
+	if err != nil {
+		return v, err
+	}
+
+	scale := func(x float32) float32 {
+		return float32(float64(x) * factor)
+	}
+
+	v.Value = scale(v.Value)
+	v.NormalRange = ConvertRange(v.NormalRange, factor)
+	v.Units = to
+	v.UCUM, _ = UCUM(to)
+	return v, nil
+}
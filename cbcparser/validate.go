@@ -0,0 +1,331 @@
+package cbcparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMissingRange  = errors.New("missing range")
+	ErrInvertedRange = errors.New("lower limit is greater than upper limit")
+	ErrNegativeRange = errors.New("negative limit")
+	ErrCriticalRange = errors.New("critical limit inside the normal range")
+	ErrInvalidSex    = errors.New(`sex must be "male", "female" or empty`)
+	ErrInvalidAges   = errors.New("min_age is not below max_age")
+	ErrUnknownField  = errors.New("unknown field")
+)
+
+// RangeError is an invalid range in a normal ranges file.
+type RangeError struct {
+	Key string // JSON key of the range e.g "lym_percent"
+	Err error
+}
+
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Key, e.Err)
+}
+
+func (e *RangeError) Unwrap() error {
+	return e.Err
+}
+
+// RangeErrors lists every invalid range of a normal ranges file.
+type RangeErrors []*RangeError
+
+func (errs RangeErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return "invalid normal ranges:\n\t" + strings.Join(msgs, "\n\t")
+}
+
+// Is reports whether any of errs matches target e.g ErrMissingRange.
+func (errs RangeErrors) Is(target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsZero reports whether nr is unset.
+func (nr NormalRange) IsZero() bool {
+	return nr == NormalRange{}
+}
+
+// Validate returns an error if the limits of nr are negative or out of order.
+func (nr NormalRange) Validate() error {
+	switch {
+	case nr.Lower < 0 || nr.Upper < 0 || nr.CriticalLower < 0 || nr.CriticalUpper < 0:
+		return ErrNegativeRange
+	case nr.Lower > nr.Upper:
+		return ErrInvertedRange
+	case nr.CriticalLower != 0 && nr.CriticalLower > nr.Lower:
+		return ErrCriticalRange
+	case nr.CriticalUpper != 0 && nr.CriticalUpper < nr.Upper:
+		return ErrCriticalRange
+	}
+	return nil
+}
+
+// Validate checks every range of nr and that the required parameters have a
+// range e.g the parameters a machine reports(see human.Parameters). All
+// invalid ranges are returned together as RangeErrors.
+func (nr *CBCNormalRange) Validate(required ...Parameter) error {
+	var errs RangeErrors
+	for _, p := range Parameters() {
+		r := nr.Range(p)
+		if r.IsZero() {
+			if contains(required, p) {
+				errs = append(errs, &RangeError{Key: p.Key(), Err: ErrMissingRange})
+			}
+			continue
+		}
+
+		if err := r.Validate(); err != nil {
+			errs = append(errs, &RangeError{Key: p.Key(), Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ReadNormalRanges reads and validates a normal ranges file. Unknown keys and fields
+// and invalid ranges are reported together as RangeErrors. Parameters without a
+// range are allowed, use Validate to require them.
+func ReadNormalRanges(r io.Reader) (*CBCNormalRange, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var normal_ranges CBCNormalRange
+	if err := json.Unmarshal(data, &normal_ranges); err != nil {
+		return nil, err
+	}
+
+	_, errs := clean_ranges(data, "")
+	if err := normal_ranges.Validate(); err != nil {
+		errs = append(errs, err.(RangeErrors)...)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return &normal_ranges, nil
+}
+
+// clean_ranges reports the unknown keys of the ranges at scope e.g "default."
+// and the unknown fields of each range, and returns data without them.
+func clean_ranges(data json.RawMessage, scope string) (json.RawMessage, RangeErrors) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return json.RawMessage("{}"), RangeErrors{{Key: strings.TrimSuffix(scope, "."), Err: err}}
+	}
+
+	var errs RangeErrors
+	out := make(map[string]json.RawMessage, len(keys))
+	for _, key := range sorted_keys(keys) {
+		if _, err := ParseParameter(key); err != nil {
+			errs = append(errs, &RangeError{Key: scope + key, Err: err})
+			continue
+		}
+
+		var nr NormalRange
+		if err := strict_unmarshal(keys[key], &nr); err != nil {
+			errs = append(errs, &RangeError{Key: scope + key, Err: err})
+			continue
+		}
+		out[key] = keys[key]
+	}
+	return marshal_raw(out), errs
+}
+
+// clean_reference reports the unknown fields and keys of partitioned reference
+// ranges, their partitions and species, and returns data without them so that
+// the remaining ranges can still be validated.
+func clean_reference(data json.RawMessage, prefix string) (json.RawMessage, RangeErrors) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return json.RawMessage("{}"), RangeErrors{{Key: strings.TrimSuffix(prefix, "."), Err: err}}
+	}
+
+	var errs RangeErrors
+	out := make(map[string]json.RawMessage, len(keys))
+	for _, key := range sorted_keys(keys) {
+		switch key {
+		case "default":
+			ranges, e := clean_ranges(keys[key], prefix+"default.")
+			errs = append(errs, e...)
+			out[key] = ranges
+		case "partitions":
+			var partitions []json.RawMessage
+			if err := json.Unmarshal(keys[key], &partitions); err != nil {
+				errs = append(errs, &RangeError{Key: prefix + key, Err: err})
+				continue
+			}
+
+			// Invalid partitions are kept empty so that the others keep their index.
+			for i, pt := range partitions {
+				var e RangeErrors
+				partitions[i], e = clean_partition(pt, prefix, i)
+				errs = append(errs, e...)
+			}
+			out[key] = marshal_raw(partitions)
+		case "species":
+			var species map[string]json.RawMessage
+			if err := json.Unmarshal(keys[key], &species); err != nil {
+				errs = append(errs, &RangeError{Key: prefix + key, Err: err})
+				continue
+			}
+
+			for _, name := range sorted_keys(species) {
+				var e RangeErrors
+				species[name], e = clean_reference(species[name], prefix+"species."+name+".")
+				errs = append(errs, e...)
+			}
+			out[key] = marshal_raw(species)
+		default:
+			errs = append(errs, &RangeError{Key: prefix + key, Err: ErrUnknownField})
+		}
+	}
+	return marshal_raw(out), errs
+}
+
+// clean_partition reports the unknown and invalid fields of the i-th partition
+// and returns it without them.
+func clean_partition(data json.RawMessage, prefix string, i int) (json.RawMessage, RangeErrors) {
+	scope := fmt.Sprintf("%spartitions[%d].", prefix, i)
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return json.RawMessage("{}"), RangeErrors{{Key: strings.TrimSuffix(scope, "."), Err: err}}
+	}
+
+	var name string
+	if json.Unmarshal(keys["name"], &name) == nil && name != "" {
+		scope = fmt.Sprintf("%spartitions[%s].", prefix, name)
+	}
+
+	var errs RangeErrors
+	out := make(map[string]json.RawMessage, len(keys))
+	for _, key := range sorted_keys(keys) {
+		var err error
+		switch key {
+		case "name":
+			err = json.Unmarshal(keys[key], new(string))
+		case "sex":
+			err = json.Unmarshal(keys[key], new(Sex))
+		case "min_age", "max_age":
+			err = json.Unmarshal(keys[key], new(Age))
+		case "ranges":
+			ranges, e := clean_ranges(keys[key], scope)
+			errs = append(errs, e...)
+			out[key] = ranges
+			continue
+		default:
+			err = ErrUnknownField
+		}
+
+		if err != nil {
+			errs = append(errs, &RangeError{Key: scope + key, Err: err})
+			continue
+		}
+		out[key] = keys[key]
+	}
+	return marshal_raw(out), errs
+}
+
+func sorted_keys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// marshal_raw encodes v, which only holds JSON that was already decoded.
+func marshal_raw(v interface{}) json.RawMessage {
+	data, _ := json.Marshal(v)
+	return data
+}
+
+func strict_unmarshal(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// Validate checks every range of rr, its partitions and species.
+// All invalid ranges are returned together as RangeErrors.
+func (rr *ReferenceRanges) Validate() error {
+	errs := rr.validate("")
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (rr *ReferenceRanges) validate(prefix string) RangeErrors {
+	var errs RangeErrors
+	check := func(scope string, ranges map[Parameter]NormalRange) {
+		for _, p := range Parameters() {
+			if r, ok := ranges[p]; ok {
+				if err := r.Validate(); err != nil {
+					errs = append(errs, &RangeError{Key: scope + p.Key(), Err: err})
+				}
+			}
+		}
+	}
+
+	// Ages are compared as the dates they are reached from the same birth date.
+	birth := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i, pt := range rr.Partitions {
+		scope := fmt.Sprintf("%spartitions[%d].", prefix, i)
+		if pt.Name != "" {
+			scope = fmt.Sprintf("%spartitions[%s].", prefix, pt.Name)
+		}
+
+		if pt.Sex != SexUnknown && pt.Sex != Male && pt.Sex != Female {
+			errs = append(errs, &RangeError{Key: scope + "sex", Err: ErrInvalidSex})
+		}
+
+		if !pt.MaxAge.IsZero() && !pt.MinAge.After(birth).Before(pt.MaxAge.After(birth)) {
+			errs = append(errs, &RangeError{Key: scope + "min_age", Err: ErrInvalidAges})
+		}
+		check(scope, pt.Ranges)
+	}
+	check(prefix+"default.", rr.Default)
+
+	names := make([]string, 0, len(rr.Species))
+	for name := range rr.Species {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		errs = append(errs, rr.Species[name].validate(prefix+"species."+name+".")...)
+	}
+	return errs
+}
+
+func contains(params []Parameter, p Parameter) bool {
+	for _, q := range params {
+		if q == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package cbcparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ValueStatus describes whether a CBCValue holds a measured value.
+type ValueStatus int
+
+const (
+	// The cell contained a valid number.
+	StatusOK ValueStatus = iota
+	// The cell was empty.
+	StatusMissing
+	// The cell contained text that is not a number.
+	StatusUnparseable
+	// The machine withheld the value e.g "***" or "---".
+	StatusSuppressed
+	// The value was outside the plausibility limits.
+	StatusRejected
+	// The value was outside the measuring range of the instrument
+	// and is reported as the limit it exceeded.
+	StatusAboveRange
+	StatusBelowRange
+)
+
+var statusNames = [...]string{
+	StatusOK:          "ok",
+	StatusMissing:     "missing",
+	StatusUnparseable: "unparseable",
+	StatusSuppressed:  "suppressed",
+	StatusRejected:    "rejected",
+	StatusAboveRange:  "above_range",
+	StatusBelowRange:  "below_range",
+}
+
+func (s ValueStatus) String() string {
+	if s < 0 || int(s) >= len(statusNames) {
+		return fmt.Sprintf("ValueStatus(%d)", int(s))
+	}
+	return statusNames[s]
+}
+
+func (s ValueStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *ValueStatus) UnmarshalText(text []byte) error {
+	for i, name := range statusNames {
+		if name == string(text) {
+			*s = ValueStatus(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown value status %q", text)
+}
+
+// ParseValue parses a numeric cell and reports whether it holds a value.
+// Cells made up only of '*' or '-' are treated as suppressed by the machine.
+func ParseValue(cell string) (float32, ValueStatus) {
+	cell = strings.TrimSpace(cell)
+	if cell == "" {
+		return 0, StatusMissing
+	}
+
+	if strings.Trim(cell, "*-") == "" {
+		return 0, StatusSuppressed
+	}
+
+	fvalue, err := strconv.ParseFloat(cell, 32)
+	if err != nil {
+		return 0, StatusUnparseable
+	}
+	return float32(fvalue), StatusOK
+}
+
+// Valid reports whether v holds a measured value.
+func (v CBCValue) Valid() bool {
+	return v.Status == StatusOK
+}
+
+// MarshalJSON encodes the value as null when v holds no measured value
+// or limit of the measuring range.
+func (v CBCValue) MarshalJSON() ([]byte, error) {
+	type cbcValue CBCValue // prevent recursion
+
+	out := struct {
+		Value *float32 `json:"value"`
+		cbcValue
+	}{cbcValue: cbcValue(v)}
+
+	if v.Valid() || v.Comparator != "" {
+		out.Value = &v.Value
+	}
+	return json.Marshal(out)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/abiiranathan/cbcparser/cbcparser"
+	_ "github.com/abiiranathan/cbcparser/cbcparser/edan"
+	_ "github.com/abiiranathan/cbcparser/cbcparser/human"
+)
+
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Printf("Usage: %s <normal_ranges_file> <cbc_file>...\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	f, err := os.Open(os.Args[1])
+	if err != nil {
+		log.Fatalf("open error: %s\n", err)
+	}
+	defer f.Close()
+
+	normal_ranges, err := cbcparser.ReadNormalRanges(f)
+	if err != nil {
+		log.Fatalf("read normal ranges error: %s\n", err)
+	}
+
+	for _, name := range os.Args[2:] {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Fatalf("open error: %s\n", err)
+		}
+
+		// The machine is detected from the header line.
+		results, err := cbcparser.ParseAuto(f, normal_ranges)
+		f.Close()
+
+		if err != nil {
+			log.Fatalf("%s: parse error: %s\n", name, err)
+		}
+
+		for _, res := range results {
+			wbc, _ := res.Value(cbcparser.WBC)
+			fmt.Printf("%s\t%s\t%s\tWBC: %.2f %s\n", name, res.Instrument.Model, res.SampleID, wbc.Value, wbc.Units)
+		}
+	}
+}
@@ -42,14 +42,10 @@ func main() {
 		log.Fatalf("parse error: %s\n", err)
 	}
 
-	if v, ok := w.(edan.EdanCBCResult); ok {
-		fmt.Println(v)
-	}
-
-	// err = w.Write(os.Stdout, cbcparser.JSON)
+	err = w.Write(os.Stdout, cbcparser.JSONIndent)
 
-	// if err != nil {
-	// 	fmt.Println(err)
-	// 	os.Exit(1)
-	// }
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 }
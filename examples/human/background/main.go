@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/abiiranathan/cbcparser/cbcparser"
+	"github.com/abiiranathan/cbcparser/cbcparser/background"
+	"github.com/abiiranathan/cbcparser/cbcparser/human"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Printf("Usage: %s <cbc_file>\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	f, err := os.Open(os.Args[1])
+	if err != nil {
+		log.Fatalf("open error: %s\n", err)
+	}
+	defer f.Close()
+
+	// Blank runs are needed to check the background counts.
+	results, err := human.NewMultiParser().ParseMulti(f, nil,
+		cbcparser.WithRecordTypes(cbcparser.RecordPatient, cbcparser.RecordBlank))
+
+	if err != nil {
+		log.Fatalf("parse error: %s\n", err)
+	}
+
+	days := background.Process(results, background.DefaultLimits())
+	data, err := json.MarshalIndent(days, "", "  ")
+	if err != nil {
+		log.Fatalf("json error: %s\n", err)
+	}
+	fmt.Println(string(data))
+
+	for _, res := range results {
+		for _, alarm := range res.Alarms {
+			if alarm.Source == cbcparser.SourceBackground {
+				fmt.Printf("%s: %s\n", res.SampleID, alarm)
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/abiiranathan/cbcparser/cbcparser"
+	"github.com/abiiranathan/cbcparser/cbcparser/human"
+)
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Printf("Usage: %s <cbc_file> <normal_ranges_file>\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	f, err := os.Open(os.Args[1])
+	if err != nil {
+		log.Fatalf("open error: %s\n", err)
+	}
+
+	f2, err := os.Open(os.Args[2])
+	if err != nil {
+		log.Fatalf("open error: %s\n", err)
+	}
+
+	defer f.Close()
+	defer f2.Close()
+
+	normal_ranges, err := cbcparser.ReadNormalRanges(f2)
+	if err != nil {
+		log.Fatalf("read normal ranges error: %s\n", err)
+	}
+
+	// Records are read one row at a time.
+	reader := human.NewReader(f, normal_ranges)
+	for {
+		res, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			log.Fatalf("parse error: %s\n", err)
+		}
+
+		when := "unknown date"
+		if res.Timestamp != nil {
+			when = res.Timestamp.Format("2006-01-02 15:04")
+		}
+
+		hgb, _ := res.Value(cbcparser.HGB)
+		if !hgb.Valid() {
+			fmt.Printf("%s\t%s\tHGB: %s\n", res.SampleID, when, hgb.Status)
+			continue
+		}
+		fmt.Printf("%s\t%s\tHGB: %.1f %s\n", res.SampleID, when, hgb.Value, hgb.Units)
+	}
+}